@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	aggregatorURL string
+	agentRegion   string
+	serveRegion   string
+	agentToken    string
+)
+
+// agentReport is the report of a run posted by an agent to the
+// aggregator
+type agentReport struct {
+	Region string `json:"region"`
+	Report report `json:"report"`
+}
+
+// regionReport is the latest report of a vantage point as exposed by
+// the aggregator
+type regionReport struct {
+	Region     string    `json:"region"`
+	ReceivedAt time.Time `json:"received_at"`
+	Report     report    `json:"report"`
+}
+
+// regionReports keeps the latest report of each agent
+type regionReports struct {
+	mu      sync.RWMutex
+	reports map[string]regionReport
+}
+
+func newRegionReports() *regionReports {
+	return &regionReports{reports: map[string]regionReport{}}
+}
+
+func (r *regionReports) add(ar agentReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports[ar.Region] = regionReport{Region: ar.Region, ReceivedAt: time.Now().UTC(), Report: ar.Report}
+}
+
+// all returns the latest report of each agent sorted by region
+func (r *regionReports) all() []regionReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]regionReport, 0, len(r.reports))
+	for _, rr := range r.reports {
+		all = append(all, rr)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Region < all[j].Region })
+	return all
+}
+
+// registerAgents receives the reports of the agents and exposes them
+// with the local results:
+//
+//	POST /api/v1/agents   report of an agent
+//	GET  /api/v1/regions  latest report of each vantage point
+func registerAgents(mux *http.ServeMux, network string, history *runHistory, regions *regionReports) {
+	mux.HandleFunc("/api/v1/agents", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if len(agentToken) > 0 {
+			auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(auth), []byte(agentToken)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var ar agentReport
+		if err := json.NewDecoder(io.LimitReader(r.Body, 10<<20)).Decode(&ar); err != nil {
+			http.Error(w, "invalid report: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch {
+		case len(ar.Region) <= 0:
+			http.Error(w, "missing region", http.StatusBadRequest)
+			return
+		case ar.Region == serveRegion:
+			http.Error(w, "region of the aggregator", http.StatusBadRequest)
+			return
+		case ar.Report.SchemaVersion != jsonSchemaVersion:
+			http.Error(w, fmt.Sprintf("unsupported schema version %d", ar.Report.SchemaVersion), http.StatusBadRequest)
+			return
+		case ar.Report.Network != network:
+			http.Error(w, "report of network "+ar.Report.Network, http.StatusBadRequest)
+			return
+		}
+		regions.add(ar)
+		slog.Debug("agent report received", "region", ar.Region)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/api/v1/regions", func(w http.ResponseWriter, r *http.Request) {
+		all := regions.all()
+		if last, ok := history.latest(); ok {
+			all = append([]regionReport{{
+				Region:     serveRegion,
+				ReceivedAt: last.Timestamp.UTC(),
+				Report:     newReport(network, last.Timestamp, last.Results, nil),
+			}}, all...)
+		}
+		writeJSON(w, all)
+	})
+}
+
+func newAgentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run the checks every --interval and report them to an aggregator started with serve",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			network, cfg := loadConfig()
+			checks = selectChecks(cfg.Checks)
+			agent(network, selectValidators(cfg.Validators))
+		},
+	}
+	fs := cmd.Flags()
+	addRunFlags(cmd)
+	addLoopFlags(fs)
+	fs.StringVar(&aggregatorURL, "aggregator", "", "url of the aggregator (e.g: http://checks.example.com:8080)")
+	fs.StringVar(&agentRegion, "region", "", "name of the vantage point of the agent (e.g: eu-west)")
+	fs.StringVar(&agentToken, "agent-token", "", "token authenticating the agent to the aggregator")
+	_ = cmd.MarkFlagRequired("aggregator")
+	_ = cmd.MarkFlagRequired("region")
+	return cmd
+}
+
+// agent runs the checks every interval forever, posting each report
+// to the aggregator
+func agent(network string, validators []validator) {
+	url := strings.TrimSuffix(aggregatorURL, "/") + "/api/v1/agents"
+	headers := map[string]string{}
+	if len(agentToken) > 0 {
+		headers["Authorization"] = "Bearer " + agentToken
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		startedAt := time.Now()
+		ctx, cancel := runContext()
+		res := runChecks(ctx, validators, checks, nil)
+		cancel()
+		pushMetrics(network, run{Timestamp: startedAt, Results: res})
+
+		ctx, cancel = context.WithTimeout(context.Background(), notifyTimeout)
+		err := postJSON(ctx, url, headers, agentReport{
+			Region: agentRegion,
+			Report: newReport(network, startedAt, res, nil),
+		})
+		cancel()
+		if err != nil {
+			slog.Error("could not report to the aggregator", "error", err)
+		} else {
+			slog.Info("checks reported", "duration", time.Since(startedAt))
+		}
+		<-ticker.C
+	}
+}
+
+func newRegionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "regions",
+		Short: "Show the latency of each validator api from every vantage point of an aggregator",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			showRegions()
+		},
+	}
+	cmd.Flags().StringVar(&aggregatorURL, "aggregator", "", "url of the aggregator (e.g: http://checks.example.com:8080)")
+	_ = cmd.MarkFlagRequired("aggregator")
+	return cmd
+}
+
+func showRegions() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(aggregatorURL, "/")+"/api/v1/regions", nil)
+	if err != nil {
+		fatalConfig("invalid aggregator url: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fatalConfig("could not reach the aggregator: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fatalConfig("unexpected http status code: %v", resp.StatusCode)
+	}
+	var regions []regionReport
+	if err := json.NewDecoder(resp.Body).Decode(&regions); err != nil {
+		fatalConfig("invalid response: %v", err)
+	}
+	if len(regions) <= 0 {
+		fmt.Println("no results yet")
+		return
+	}
+
+	fmt.Println(renderRegions(regions))
+}
+
+// renderRegions renders a row per validator api and a column per
+// region
+func renderRegions(regions []regionReport) string {
+	type key struct{ validator, api string }
+	var (
+		rows    []key
+		seen    = map[key]bool{}
+		results = map[key]map[string]apiReport{}
+	)
+	for _, rr := range regions {
+		for _, v := range rr.Report.Validators {
+			for _, a := range v.APIResults {
+				k := key{v.Name, a.API}
+				if !seen[k] {
+					seen[k] = true
+					rows = append(rows, k)
+					results[k] = map[string]apiReport{}
+				}
+				results[k][rr.Region] = a
+			}
+		}
+	}
+
+	header := table.Row{"validator", "api"}
+	for _, rr := range regions {
+		header = append(header, rr.Region)
+	}
+	t := table.NewWriter()
+	t.AppendHeader(header)
+	for _, k := range rows {
+		row := table.Row{k.validator, apiHeader(k.api)}
+		for _, rr := range regions {
+			a, ok := results[k][rr.Region]
+			if !ok {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, coloredDuration(aPIResult{API: a.API, TimeTaken: fromMS(a.TimeTakenMS), Error: a.Error, Attempts: a.Attempts}))
+		}
+		t.AppendRow(row)
+	}
+	return t.Render()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// registerAPI exposes the results as json documents:
+//
+//	/api/v1/results              latest run
+//	/api/v1/results/{validator}  latest run of a single validator
+//	/api/v1/history?limit=N      runs kept in memory, oldest first
+func registerAPI(mux *http.ServeMux, network string, history *runHistory) {
+	mux.HandleFunc("/api/v1/results", func(w http.ResponseWriter, r *http.Request) {
+		last, ok := history.latest()
+		if !ok {
+			http.Error(w, "no results yet", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, newReport(network, last.Timestamp, last.Results, nil))
+	})
+
+	mux.HandleFunc("/api/v1/results/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/v1/results/")
+		last, ok := history.latest()
+		if !ok {
+			http.Error(w, "no results yet", http.StatusServiceUnavailable)
+			return
+		}
+		for _, v := range newReport(network, last.Timestamp, last.Results, nil).Validators {
+			if strings.EqualFold(v.Name, name) {
+				writeJSON(w, v)
+				return
+			}
+		}
+		http.Error(w, "unknown validator", http.StatusNotFound)
+	})
+
+	mux.HandleFunc("/api/v1/history", func(w http.ResponseWriter, r *http.Request) {
+		runs := history.all()
+		if l := r.URL.Query().Get("limit"); len(l) > 0 {
+			limit, err := strconv.Atoi(l)
+			if err != nil || limit < 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			if limit < len(runs) {
+				runs = runs[len(runs)-limit:]
+			}
+		}
+
+		reports := make([]report, 0, len(runs))
+		for _, run := range runs {
+			reports = append(reports, newReport(network, run.Timestamp, run.Results, nil))
+		}
+		writeJSON(w, reports)
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"time"
+)
+
+var (
+	baselineFactor     float64
+	baselineWindow     string
+	baselineMinSamples int
+	baselineAlert      bool
+)
+
+// baselineRegressions returns the checks of the run slower than
+// --baseline-factor times their median latency over the stored runs of
+// the last --baseline-window, nil if the store or the factor is not set
+func baselineRegressions(st *store, network string, res []results) ([]diffEntry, error) {
+	if st == nil || baselineFactor <= 0 {
+		return nil, nil
+	}
+	since, err := parseSince(baselineWindow)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := st.results(network, since)
+	if err != nil {
+		return nil, err
+	}
+
+	latencies := map[string][]time.Duration{}
+	for _, r := range stored {
+		if r.Status == apiStatusError {
+			continue
+		}
+		key := r.Validator + "/" + r.API
+		latencies[key] = append(latencies[key], fromMS(r.LatencyMS))
+	}
+
+	var regressions []diffEntry
+	for _, v := range res {
+		for _, a := range v.APIResults {
+			l := latencies[v.Name+"/"+a.API]
+			if len(a.Error) > 0 || len(l) < baselineMinSamples || len(l) <= 0 {
+				continue
+			}
+			baseline := percentile(l, 50)
+			if float64(a.TimeTaken) > float64(baseline)*baselineFactor {
+				regressions = append(regressions, diffEntry{
+					Name:     v.Name,
+					API:      a.API,
+					Change:   changeBaseline,
+					BeforeMS: toMS(baseline),
+					AfterMS:  toMS(a.TimeTaken),
+				})
+			}
+		}
+	}
+	return regressions, nil
+}
+
+// alertedChanges returns the changes sent to the notifiers, which
+// include the baseline regressions only with --baseline-alert
+func alertedChanges(changes []diffEntry) []diffEntry {
+	if changes == nil || baselineAlert {
+		return changes
+	}
+	alerted := []diffEntry{}
+	for _, c := range changes {
+		if c.Change != changeBaseline {
+			alerted = append(alerted, c)
+		}
+	}
+	return alerted
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// cdnSignatures identifies the CDNs and WAFs from the headers they add
+// to the responses, in order of precedence
+var cdnSignatures = []struct {
+	name  string
+	match func(h http.Header) bool
+}{
+	{"cloudflare", func(h http.Header) bool {
+		return len(h.Get("CF-Ray")) > 0 || strings.EqualFold(h.Get("Server"), "cloudflare")
+	}},
+	{"cloudfront", func(h http.Header) bool {
+		return len(h.Get("X-Amz-Cf-Id")) > 0 || strings.Contains(h.Get("Via"), "CloudFront")
+	}},
+	{"fastly", func(h http.Header) bool {
+		return len(h.Get("X-Fastly-Request-ID")) > 0 || strings.Contains(h.Get("Via"), "varnish") && len(h.Get("X-Served-By")) > 0
+	}},
+	{"akamai", func(h http.Header) bool {
+		return strings.HasPrefix(h.Get("Server"), "AkamaiGHost") || len(h.Get("X-Akamai-Transformed")) > 0
+	}},
+	{"imperva", func(h http.Header) bool {
+		return len(h.Get("X-Iinfo")) > 0 || strings.Contains(h.Get("X-CDN"), "Incapsula")
+	}},
+	{"sucuri", func(h http.Header) bool {
+		return len(h.Get("X-Sucuri-ID")) > 0
+	}},
+	{"ddos-guard", func(h http.Header) bool {
+		return strings.EqualFold(h.Get("Server"), "ddos-guard")
+	}},
+	{"azure front door", func(h http.Header) bool {
+		return len(h.Get("X-Azure-Ref")) > 0
+	}},
+	{"google cloud cdn", func(h http.Header) bool {
+		return strings.Contains(h.Get("Via"), "google")
+	}},
+}
+
+// detectCDN returns the CDN or WAF in front of the endpoint which sent
+// the response headers, empty if none is recognized
+func detectCDN(h http.Header) string {
+	for _, s := range cdnSignatures {
+		if s.match(h) {
+			return s.name
+		}
+	}
+	return ""
+}
+
+// isChallenge returns whether the response is a challenge of a WAF
+// rather than the response of the api
+func isChallenge(resp *http.Response) bool {
+	return len(resp.Header.Get("CF-Mitigated")) > 0 ||
+		len(resp.Header.Get("X-Sucuri-Block")) > 0 ||
+		(resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusServiceUnavailable) &&
+			strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html")
+}
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dnapipb "code.vegaprotocol.io/vega/protos/data-node/api/v2"
+	apipb "code.vegaprotocol.io/vega/protos/vega/api/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func init() {
+	register(apiCheck{"core", func(v validator) string { return v.GRPC }, checkGRPC}, profileQuick)
+	register(apiCheck{"datanode", func(v validator) string { return v.GRPC }, checkGRPCDN}, profileQuick)
+	register(apiCheck{"rest", func(v validator) string { return v.REST }, checkREST}, profileQuick)
+	register(apiCheck{"gql", func(v validator) string { return v.GQL }, checkGQL}, profileQuick)
+}
+
+const gqlPayload = `{"query": "{epoch{id}}"}`
+
+const (
+	detailBlockHeight = "block_height"
+	detailVersion     = "version"
+	detailChainID     = "chain_id"
+	detailRedirects   = "redirects"
+	detailFinalURL    = "final_url"
+	detailRedirect    = "redirect_warning"
+	detailCDN         = "cdn"
+	detailIP          = "ip"
+	detailChainStatus = "chain_status"
+	detailPeers       = "peers"
+	detailPeersWarn   = "peers_warning"
+	detailBacklog     = "backlog_length"
+	detailTxPerBlock  = "tx_per_block"
+	// detailNotConfigured is set by the optional checks on the
+	// validators without the endpoint they probe
+	detailNotConfigured = "not_configured"
+)
+
+// maxBacklog is the length of the transaction backlog above which the
+// core check fails, unbounded if 0
+var maxBacklog uint64
+
+// grpcCheckTimeout returns the timeout of the grpc checks
+func grpcCheckTimeout() time.Duration {
+	if grpcTimeout > 0 {
+		return grpcTimeout
+	}
+	return timeout
+}
+
+// httpCheckTimeout returns the timeout of the http checks
+func httpCheckTimeout() time.Duration {
+	if httpTimeout > 0 {
+		return httpTimeout
+	}
+	return timeout
+}
+
+// httpClient does not keep connections alive so every check pays for
+// its own connection establishment, making timings comparable
+var (
+	httpTransport = &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DisableKeepAlives: true,
+		ForceAttemptHTTP2: true,
+		TLSClientConfig:   clientTLS,
+		DialContext:       dialContext,
+	}
+	httpClient = &http.Client{Transport: httpTransport}
+)
+
+// timings splits the time taken by a check between the connection
+// establishment steps and the request itself
+type timings struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	Request time.Duration
+}
+
+func (t timings) total() time.Duration {
+	return t.DNS + t.Connect + t.TLS + t.Request
+}
+
+type validatorKey struct{}
+
+// withValidator attaches the validator to the context of its checks,
+// its headers and tls server name applying to their requests
+func withValidator(ctx context.Context, v validator) context.Context {
+	return context.WithValue(ctx, validatorKey{}, v)
+}
+
+func headersFrom(ctx context.Context) map[string]string {
+	v, _ := ctx.Value(validatorKey{}).(validator)
+	return v.Headers
+}
+
+// serverName returns the name the certificates of the validator are
+// verified against, the host dialed unless overridden
+func serverName(ctx context.Context, host string) string {
+	if v, _ := ctx.Value(validatorKey{}).(validator); len(v.ServerName) > 0 {
+		return v.ServerName
+	}
+	return host
+}
+
+// httpClientFor returns the client sending the requests of the checks,
+// verifying the certificates against the server name of the validator
+// if overridden
+func httpClientFor(ctx context.Context) *http.Client {
+	v, _ := ctx.Value(validatorKey{}).(validator)
+	if len(v.ServerName) <= 0 {
+		return httpClient
+	}
+	t := httpTransport.Clone()
+	t.TLSClientConfig.ServerName = v.ServerName
+	return &http.Client{Transport: t}
+}
+
+// applyHeaders sets the headers of the validator on the request
+func applyHeaders(req *http.Request) {
+	for k, v := range headersFrom(req.Context()) {
+		if strings.EqualFold(k, "host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+}
+
+// grpcMetadata sends the headers of the validator as grpc metadata,
+// Host being used as the authority of the connection instead
+func grpcMetadata(ctx context.Context) context.Context {
+	md := metadata.MD{}
+	for k, v := range headersFrom(ctx) {
+		if !strings.EqualFold(k, "host") {
+			md.Append(k, v)
+		}
+	}
+	if md.Len() <= 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// grpcAuthority returns the Host header of the validator, empty if not
+// overridden
+func grpcAuthority(ctx context.Context) string {
+	for k, v := range headersFrom(ctx) {
+		if strings.EqualFold(k, "host") {
+			return v
+		}
+	}
+	return ""
+}
+
+// httpTracer records the timings of an http request
+type httpTracer struct {
+	mu                            sync.Mutex
+	t                             timings
+	dnsStart, connStart, tlsStart time.Time
+	reqStart                      time.Time
+	remote                        net.Addr
+}
+
+func (h *httpTracer) trace() *httptrace.ClientTrace {
+	lock := func(f func()) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		f()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { lock(func() { h.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { lock(func() { h.t.DNS = time.Since(h.dnsStart) }) },
+		// with multiple addresses connections are attempted in
+		// parallel, only the first one started is accounted for
+		ConnectStart: func(string, string) {
+			lock(func() {
+				if h.connStart.IsZero() {
+					h.connStart = time.Now()
+				}
+			})
+		},
+		ConnectDone:       func(string, string, error) { lock(func() { h.t.Connect = time.Since(h.connStart) }) },
+		TLSHandshakeStart: func() { lock(func() { h.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			lock(func() { h.t.TLS = time.Since(h.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			lock(func() {
+				h.reqStart = time.Now()
+				h.remote = info.Conn.RemoteAddr()
+			})
+		},
+	}
+}
+
+// done returns the timings once the response was received, or the
+// request failed
+func (h *httpTracer) done(start time.Time) timings {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := h.t
+	if h.reqStart.IsZero() {
+		// the connection could not be established, account all the
+		// remaining time to the last step attempted
+		t.Connect = time.Since(start) - t.DNS - t.TLS
+		return t
+	}
+	t.Request = time.Since(h.reqStart)
+	return t
+}
+
+// doHTTP sends the request and records its timings, only a 200
+// response is considered successful. Redirects are followed and
+// reported in the details.
+func doHTTP(req *http.Request) (timings, map[string]string, error) {
+	return doHTTPJSON(req, nil)
+}
+
+// doHTTPJSON sends the request like doHTTP, decoding the json body of
+// successful responses into out if not nil
+func doHTTPJSON(req *http.Request, out any) (timings, map[string]string, error) {
+	return doHTTPStatus(req, out, http.StatusOK)
+}
+
+// doHTTPStatus is doHTTPJSON considering successful the responses of
+// any of the statuses
+func doHTTPStatus(req *http.Request, out any, statuses ...int) (timings, map[string]string, error) {
+	applyHeaders(req)
+
+	tracer := &httpTracer{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), tracer.trace()))
+
+	start := time.Now()
+	resp, err := httpClientFor(req.Context()).Do(req)
+	if err != nil {
+		return tracer.done(start), nil, err
+	}
+	defer resp.Body.Close()
+	t := tracer.done(start)
+	details := redirectDetails(req.URL, resp)
+	if details == nil {
+		details = map[string]string{}
+	}
+	cdn := detectCDN(resp.Header)
+	if len(cdn) > 0 {
+		details[detailCDN] = cdn
+	}
+	if ip := remoteIP(tracer.remote); len(ip) > 0 {
+		details[detailIP] = ip
+	}
+
+	if !slices.Contains(statuses, resp.StatusCode) {
+		if len(cdn) > 0 && isChallenge(resp) {
+			return t, details, fmt.Errorf("unexpected http status code: %v, blocked by a %v challenge", resp.StatusCode, cdn)
+		}
+		return t, details, fmt.Errorf("unexpected http status code: %v", resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return t, details, fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	return t, details, nil
+}
+
+// remoteIP returns the ip of the address of a peer, empty if unknown
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// redirectDetails reports the redirects followed to get the response,
+// warning about those changing the scheme or the host as they break
+// the clients which do not follow them, nil if none
+func redirectDetails(from *url.URL, resp *http.Response) map[string]string {
+	var n int
+	for r := resp.Request; r.Response != nil; r = r.Response.Request {
+		n++
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	to := resp.Request.URL
+	details := map[string]string{
+		detailRedirects: strconv.Itoa(n),
+		detailFinalURL:  to.String(),
+	}
+	switch {
+	case from.Hostname() != to.Hostname():
+		details[detailRedirect] = "redirected to another host"
+	case from.Scheme != to.Scheme:
+		details[detailRedirect] = fmt.Sprintf("redirected from %v to %v", from.Scheme, to.Scheme)
+	}
+	return details
+}
+
+// notConfigured is the result of an optional check on a validator
+// without the endpoint it probes, not failed as the embedded
+// configurations do not set the optional endpoints but left out of the
+// results by runChecks
+func notConfigured() (timings, map[string]string, error) {
+	return timings{}, map[string]string{detailNotConfigured: "true"}, nil
+}
+
+// isNotConfigured returns whether the result is the one of an optional
+// check on a validator without its endpoint
+func isNotConfigured(r aPIResult) bool {
+	return r.Details[detailNotConfigured] == "true"
+}
+
+// getJSON queries the path of the http api at address, decoding the
+// json response in out
+func getJSON(ctx context.Context, address, path string, query url.Values, out any) (timings, map[string]string, error) {
+	return getJSONTimeout(ctx, httpCheckTimeout(), address, path, query, out)
+}
+
+// getJSONTimeout is getJSON with a timeout other than the one of the
+// http checks
+func getJSONTimeout(ctx context.Context, timeout time.Duration, address, path string, query url.Values, out any) (timings, map[string]string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return timings{}, nil, err
+	}
+	u = u.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return timings{}, nil, err
+	}
+	return doHTTPJSON(req, out)
+}
+
+func checkREST(ctx context.Context, address string) (timings, map[string]string, error) {
+	s, err := url.JoinPath(address, "api/v2/info")
+	if err != nil {
+		return timings{}, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, httpCheckTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s, nil)
+	if err != nil {
+		return timings{}, nil, err
+	}
+
+	return doHTTP(req)
+}
+
+func checkGQL(ctx context.Context, address string) (timings, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, httpCheckTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewBuffer([]byte(gqlPayload)))
+	if err != nil {
+		return timings{}, nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	return doHTTP(req)
+}
+
+// dialGRPC establishes the connection to a grpc address step by step
+// to time each of them, the connection is then handed over to grpc.
+// Addresses prefixed with tls:// are dialed using TLS.
+func dialGRPC(ctx context.Context, address string) (*grpc.ClientConn, timings, error) {
+	var t timings
+
+	useTLS := strings.HasPrefix(address, "tls://")
+	address = strings.TrimPrefix(address, "tls://")
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, t, err
+	}
+
+	start := time.Now()
+	ips, err := resolver.LookupIP(ctx, "ip"+ipFamily(), host)
+	t.DNS = time.Since(start)
+	if err != nil {
+		return nil, t, err
+	}
+
+	start = time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ips[0].String(), port))
+	t.Connect = time.Since(start)
+	if err != nil {
+		return nil, t, err
+	}
+
+	if useTLS {
+		cfg := clientTLS.Clone()
+		cfg.ServerName = serverName(ctx, host)
+		cfg.NextProtos = []string{"h2"}
+		tlsConn := tls.Client(conn, cfg)
+		start = time.Now()
+		handshakeCtx, cancel := connectContext(ctx)
+		err = tlsConn.HandshakeContext(handshakeCtx)
+		cancel()
+		t.TLS = time.Since(start)
+		if err != nil {
+			conn.Close()
+			return nil, t, err
+		}
+		conn = tlsConn
+	}
+
+	// the connection is already secured if required, grpc only needs
+	// to speak http2 over it, and must not reconnect on its own
+	conns := make(chan net.Conn, 1)
+	conns <- conn
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			select {
+			case c := <-conns:
+				return c, nil
+			default:
+				return nil, errors.New("connection lost")
+			}
+		}),
+	}
+	if authority := grpcAuthority(ctx); len(authority) > 0 {
+		opts = append(opts, grpc.WithAuthority(authority))
+	}
+	cc, err := grpc.DialContext(ctx, address, opts...)
+	if err != nil {
+		conn.Close()
+		return nil, t, err
+	}
+
+	return cc, t, nil
+}
+
+// grpcConns shares the grpc connections between the checks of a run,
+// each address is dialed once and the connections are closed at the
+// end of the run
+type grpcConns struct {
+	mu    sync.Mutex
+	conns map[string]*grpcConn
+	// core is set when the core check runs, reporting the time taken
+	// to establish the connections
+	core bool
+}
+
+type grpcConn struct {
+	ready chan struct{}
+	cc    *grpc.ClientConn
+	t     timings
+	err   error
+}
+
+type grpcConnsKey struct{}
+
+func newGRPCConns() *grpcConns {
+	return &grpcConns{conns: map[string]*grpcConn{}}
+}
+
+func withGRPCConns(ctx context.Context, conns *grpcConns) context.Context {
+	return context.WithValue(ctx, grpcConnsKey{}, conns)
+}
+
+// get returns the connection to the address, dialing it if required.
+// Failed dials are not kept so the address is dialed again on retry.
+func (p *grpcConns) get(ctx context.Context, address string) (*grpc.ClientConn, timings, error) {
+	p.mu.Lock()
+	c, ok := p.conns[address]
+	if !ok {
+		c = &grpcConn{ready: make(chan struct{})}
+		p.conns[address] = c
+		p.mu.Unlock()
+
+		c.cc, c.t, c.err = dialGRPC(ctx, address)
+		if c.err != nil {
+			p.mu.Lock()
+			delete(p.conns, address)
+			p.mu.Unlock()
+		}
+		close(c.ready)
+		return c.cc, c.t, c.err
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-c.ready:
+		return c.cc, c.t, c.err
+	case <-ctx.Done():
+		return nil, timings{}, ctx.Err()
+	}
+}
+
+func (p *grpcConns) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for address, c := range p.conns {
+		if c.cc != nil {
+			c.cc.Close()
+		}
+		delete(p.conns, address)
+	}
+}
+
+// dialReported returns whether the time taken to establish the grpc
+// connections is reported by the core check
+func dialReported(ctx context.Context) bool {
+	conns, ok := ctx.Value(grpcConnsKey{}).(*grpcConns)
+	return ok && conns.core
+}
+
+// grpcConnection returns a connection to the address from the run
+// connections, the returned function must be called once done with it
+func grpcConnection(ctx context.Context, address string) (*grpc.ClientConn, timings, func(), error) {
+	if conns, ok := ctx.Value(grpcConnsKey{}).(*grpcConns); ok {
+		cc, t, err := conns.get(ctx, address)
+		return cc, t, func() {}, err
+	}
+
+	cc, t, err := dialGRPC(ctx, address)
+	if err != nil {
+		return nil, t, func() {}, err
+	}
+	return cc, t, func() { cc.Close() }, nil
+}
+
+// checkGRPC checks the core API, the time taken to establish the grpc
+// connection shared with the data-node check is reported here
+func checkGRPC(ctx context.Context, address string) (timings, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, grpcCheckTimeout())
+	defer cancel()
+
+	connection, t, release, err := grpcConnection(ctx, address)
+	if err != nil {
+		return t, nil, err
+	}
+	defer release()
+
+	connCore := apipb.NewCoreServiceClient(connection)
+
+	var p peer.Peer
+	reqCtx, cancelReq := requestContext(ctx)
+	defer cancelReq()
+	now := time.Now()
+	resp, err := connCore.Statistics(grpcMetadata(reqCtx), &apipb.StatisticsRequest{}, grpc.Peer(&p))
+	t.Request = time.Since(now)
+	if err != nil {
+		return t, nil, err
+	}
+
+	stats := resp.GetStatistics()
+	details := map[string]string{
+		detailBlockHeight: strconv.FormatUint(stats.GetBlockHeight(), 10),
+		detailVersion:     stats.GetAppVersion(),
+		detailChainID:     stats.GetChainId(),
+		detailChainStatus: strings.ToLower(strings.TrimPrefix(stats.GetStatus().String(), "CHAIN_STATUS_")),
+		detailPeers:       strconv.FormatUint(stats.GetTotalPeers(), 10),
+		detailBacklog:     strconv.FormatUint(stats.GetBacklogLength(), 10),
+		detailTxPerBlock:  strconv.FormatUint(stats.GetTxPerBlock(), 10),
+		detailEpoch:       strconv.FormatUint(stats.GetEpochSeq(), 10),
+		detailEpochExpiry: stats.GetEpochExpiryTime(),
+	}
+	if ip := remoteIP(p.Addr); len(ip) > 0 {
+		details[detailIP] = ip
+	}
+	// a single node network or a node which just started has no peers,
+	// still producing the heights the other commands rely on
+	if stats.GetTotalPeers() <= 0 {
+		details[detailPeersWarn] = "node has no peers"
+	}
+	if maxBacklog > 0 && stats.GetBacklogLength() > maxBacklog {
+		return t, details, fmt.Errorf("backlog of %d transactions", stats.GetBacklogLength())
+	}
+	return t, details, nil
+}
+
+// checkGRPCDN checks the data-node API, reusing the connection of the
+// core check so only the request is timed, unless the core check does
+// not run
+func checkGRPCDN(ctx context.Context, address string) (timings, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, grpcCheckTimeout())
+	defer cancel()
+
+	connection, t, release, err := grpcConnection(ctx, address)
+	if dialReported(ctx) {
+		t = timings{}
+	}
+	if err != nil {
+		return t, nil, err
+	}
+	defer release()
+
+	connDT := dnapipb.NewTradingDataServiceClient(connection)
+
+	// the data-node reports the block height it has processed
+	// in the response headers
+	var header metadata.MD
+	reqCtx, cancelReq := requestContext(ctx)
+	defer cancelReq()
+	now := time.Now()
+	resp, err := connDT.Info(grpcMetadata(reqCtx), &dnapipb.InfoRequest{}, grpc.Header(&header))
+	t.Request = time.Since(now)
+	if err != nil {
+		return t, nil, err
+	}
+
+	details := map[string]string{
+		detailVersion: resp.GetVersion(),
+	}
+	if h := header.Get("x-block-height"); len(h) > 0 {
+		details[detailBlockHeight] = h[0]
+	}
+	return t, details, nil
+}
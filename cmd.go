@@ -0,0 +1,571 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// newRootCmd returns the command line, each mode of the tool being a
+// subcommand
+func newRootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "check_validator_setup",
+		Short:         "Check the apis exposed by the vega validators",
+		Version:       version,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			// colors are already disabled by NO_COLOR or when stdout is
+			// not a terminal
+			if noColor {
+				color.NoColor = true
+			}
+			if retries < 0 {
+				return fmt.Errorf("invalid number of retries: %v", retries)
+			}
+			if retryBackoff < 0 {
+				return fmt.Errorf("invalid retry backoff: %v", retryBackoff)
+			}
+			if err := setupLogger(); err != nil {
+				return err
+			}
+			if err := setupTransport(); err != nil {
+				return err
+			}
+			return setupGeoIP()
+		},
+	}
+	root.PersistentFlags().BoolVar(&testnetConfig, "testnet", false, "check testnet")
+	root.PersistentFlags().StringVar(&configFile, "config", "", "configuration file to use instead of the embedded network configurations")
+	root.PersistentFlags().StringVar(&logLevel, "log-level", "info", "minimum level of the logs [debug|info|warn|error]")
+	root.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable the colors of the output, also disabled by $NO_COLOR or when stdout is not a terminal")
+	root.PersistentFlags().StringVar(&logFormat, "log-format", "text", "format of the logs written to stderr [text|json]")
+
+	root.AddCommand(
+		newCheckCmd(),
+		newWatchCmd(),
+		newTUICmd(),
+		newServeCmd(),
+		newAgentCmd(),
+		newRegionsCmd(),
+		newProbeCmd(),
+		newConsensusCmd(),
+		newMetadataCmd(),
+		newValidatorsCmd(),
+		newHistoryCmd(),
+		newStatusPageCmd(),
+		newConfigCmd(),
+		newVersionCmd(),
+		newCompletionCmd(),
+	)
+	// --version prints the same as the version command
+	root.SetVersionTemplate(newBuildInfo().String())
+	return root
+}
+
+func newCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Run the checks once and report the results",
+		Long: `Run the checks once and report the results.
+
+Exit status:
+  0    every check succeeded, or the results met the policy if one is set
+  1    a check failed, or the results broke the policy if one is set
+       (--fail-if-down, --fail-if-slower-than, ...)
+  2    invalid configuration or command line, or a store or output error
+  3    every validator is down, whether a policy is set or not
+  130  interrupted`,
+		Args: cobra.NoArgs,
+		PreRunE: func(*cobra.Command, []string) error {
+			return validateOutput()
+		},
+		Run: func(*cobra.Command, []string) {
+			check()
+		},
+	}
+	fs := cmd.Flags()
+	addRunFlags(cmd)
+	addOutputFlags(fs)
+	addNotifyFlags(fs)
+	addStoreFlags(fs)
+	fs.BoolVarP(&pick, "pick", "i", false, "interactively select the validators to check, ignored with --only")
+	fs.BoolVar(&failFast, "fail-fast", false, "cancel the run and exit with a non zero status on the first failed check")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the checks which would be run on each validator without running them")
+	fs.StringVar(&diffFile, "diff", "", "compare the results with a previous json output")
+	fs.Float64Var(&regressionFactor, "diff-regression-factor", 1.5, "latency increase factor reported as a regression by --diff")
+	fs.BoolVar(&quiet, "quiet", false, "no output, report through the exit status only")
+	fs.BoolVar(&policy.failOnAnyError, "fail-on-any-error", false, "exit with a non zero status if any check failed, the default unless another policy is set")
+	fs.IntVar(&policy.failIfDown, "fail-if-down", 0, "exit with a non zero status if at least N validators are down")
+	fs.DurationVar(&policy.failIfSlowerThan, "fail-if-slower-than", 0, "exit with a non zero status if any check is slower than this")
+	return cmd
+}
+
+func newWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Run the checks forever, refreshing the output every --interval",
+		Args:  cobra.NoArgs,
+		PreRunE: func(*cobra.Command, []string) error {
+			return validateOutput()
+		},
+		Run: func(*cobra.Command, []string) {
+			network, cfg := loadConfig()
+			checks = selectChecks(cfg.Checks)
+			watch(network, selectValidators(cfg.Validators), buildNotifiers(cfg), openStoreFlag())
+		},
+	}
+	fs := cmd.Flags()
+	addRunFlags(cmd)
+	addOutputFlags(fs)
+	addNotifyFlags(fs)
+	addLoopFlags(fs)
+	addStoreFlags(fs)
+	return cmd
+}
+
+func newTUICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Interactive dashboard refreshing the checks every --interval",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			network, cfg := loadConfig()
+			checks = selectChecks(cfg.Checks)
+			if err := runTUI(network, selectValidators(cfg.Validators)); err != nil {
+				fatalConfig("tui error: %v", err)
+			}
+		},
+	}
+	fs := cmd.Flags()
+	addRunFlags(cmd)
+	addLoopFlags(fs)
+	return cmd
+}
+
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the checks periodically and serve the results (web dashboard /, prometheus /metrics, json /api/v1, grafana, /healthz, /readyz)",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			network, cfg := loadConfig()
+			checks = selectChecks(cfg.Checks)
+			schedules, err := buildSchedules(cfg.Schedules)
+			if err != nil {
+				fatalConfig("invalid configuration: %v", err)
+			}
+			if err := serve(serveAddr, network, selectValidators(cfg.Validators), buildNotifiers(cfg), openStoreFlag(), schedules); err != nil {
+				fatalConfig("server error: %v", err)
+			}
+		},
+	}
+	fs := cmd.Flags()
+	addRunFlags(cmd)
+	addNotifyFlags(fs)
+	addLoopFlags(fs)
+	addStoreFlags(fs)
+	fs.StringVar(&serveAddr, "listen", ":8080", "address the server listens on")
+	fs.StringVar(&serveRegion, "region", "local", "name of the vantage point of the server when aggregating agents")
+	fs.StringVar(&agentToken, "agent-token", "", "token the agents must authenticate with, any agent is accepted if empty")
+	fs.BoolVar(&enablePprof, "pprof", false, "expose the go profiles on /debug/pprof/")
+	return cmd
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the configuration a run would use, resolved from --testnet, --config, the environment and the flags, secrets redacted",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			network, cfg := loadConfig()
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(newEffectiveConfig(network, cfg)); err != nil {
+				fatalConfig("could not print configuration: %v", err)
+			}
+		},
+	}
+	addRunFlags(show)
+	addNotifyFlags(show.Flags())
+	cmd.AddCommand(show, newConfigInitCmd())
+	return cmd
+}
+
+// addRunFlags registers the flags controlling how the checks are run
+func addRunFlags(cmd *cobra.Command) {
+	fs := cmd.Flags()
+	fs.StringSliceVar(&only, "only", nil, "comma separated or repeated names of the validators to check, all of them if not set")
+	fs.StringSliceVar(&exclude, "exclude", nil, "comma separated or repeated names of validators not to check (e.g: known outages)")
+	fs.DurationVar(&timeout, "timeout", 2*time.Second, "timeout of each check")
+	fs.DurationVar(&grpcTimeout, "grpc-timeout", 0, "timeout of the grpc checks (core, datanode), defaults to --timeout")
+	fs.DurationVar(&httpTimeout, "http-timeout", 0, "timeout of the http checks (rest, gql), defaults to --timeout")
+	fs.DurationVar(&heavyTimeout, "heavy-timeout", 10*time.Second, "timeout of the checks of the heavy data-node endpoints (rewards, transfers, ledger, archival)")
+	fs.DurationVar(&maxDuration, "max-duration", 0, "maximum duration of a run, outstanding checks are then cancelled and reported as timed out")
+	fs.IntVar(&concurrency, "concurrency", 8, "number of checks run in parallel")
+	fs.BoolVar(&shuffle, "shuffle", false, "run the checks of the validators in a random order, so that latencies are not biased towards the first ones")
+	fs.IntVar(&samples, "samples", 1, "number of times each check is run, reporting min/avg/max/p95 latencies")
+	fs.IntVar(&retries, "retries", 0, "number of times a failed check is retried")
+	fs.DurationVar(&retryBackoff, "retry-backoff", 200*time.Millisecond, "delay before the first retry, doubled on each retry")
+	fs.Var(&warnThresholds, "warn-threshold", "latency above which a check is shown as slow, optionally per api (e.g: 500ms,gql=1s)")
+	fs.Var(&critThresholds, "crit-threshold", "latency above which a check is shown as critical, optionally per api (e.g: 1s,gql=2s)")
+	fs.StringSliceVar(&enabledChecks, "checks", nil, "comma separated checks to run, overriding the configuration, those of the standard profile by default ["+strings.Join(checkNames(), "|")+"]")
+	fs.StringSliceVar(&apiFilter, "api", nil, "comma separated checks the run is restricted to among the selected ones (e.g: core,rest)")
+	fs.StringVar(&profileName, "profile", "", "run the checks of a profile [quick|standard|deep] instead of those of the configuration, ignored if --checks is set")
+	fs.StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP endpoint the runs are exported to as traces and metrics (e.g: http://localhost:4318), defaults to $OTEL_EXPORTER_OTLP_ENDPOINT")
+	fs.StringToStringVar(&otlpHeaders, "otlp-header", nil, "headers sent to the OTLP endpoint (e.g: authorization=Bearer xxx)")
+	fs.DurationVar(&maxDataAge, "max-data-age", time.Minute, "age of the newest block of a data-node above which the freshness check fails")
+	fs.DurationVar(&maxOracleAge, "max-oracle-age", 24*time.Hour, "age of the newest oracle data above which the oracle check fails")
+	fs.Uint64Var(&maxBacklog, "max-backlog", 5000, "length of the transaction backlog of a core node above which the core check fails, unbounded if 0")
+	fs.DurationVar(&epochGrace, "epoch-grace", 2*time.Minute, "time past the expiry of the current epoch after which the network is reported as stuck")
+	fs.StringVar(&openAPIPath, "openapi-path", "api/v2/swagger.json", "path of the OpenAPI document on the rest api, checked by the openapi check")
+	fs.Uint64Var(&archivalEpoch, "archival-epoch", 1, "old epoch the archival data-nodes are queried for")
+	fs.IntVar(&signingWindow, "signing-window", 20, "number of recent blocks the signing check looks at")
+	fs.IntVar(&maxMissedBlocks, "max-missed-blocks", 2, "number of blocks of the signing window a validator can miss before the signing check fails")
+	fs.BoolVar(&noProgress, "no-progress", false, "do not show the progress bar, hidden anyway when stderr is not a terminal")
+	fs.BoolVar(&precheck, "precheck", false, "ping the hosts and connect to their ports before the api checks, telling hosts down from services failing, only tcp deciding reachability as unanswered pings are a warning")
+	addTransportFlags(fs)
+	addGeoIPFlags(fs)
+	_ = cmd.RegisterFlagCompletionFunc("only", completeValidators)
+	_ = cmd.RegisterFlagCompletionFunc("exclude", completeValidators)
+	_ = cmd.RegisterFlagCompletionFunc("profile", cobra.FixedCompletions(profileNames, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("checks", completeChecks)
+	_ = cmd.RegisterFlagCompletionFunc("api", completeChecks)
+}
+
+// addOutputFlags registers the flags controlling how the results are
+// printed
+func addOutputFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&output, "output", "human", "results output [human|emoji|json|ndjson|checkmk|tap|github|template], github writing annotations and the job summary of github actions")
+	fs.StringVar(&templateFile, "template", "", "go template file used by the template output, executed with the json report")
+	fs.StringSliceVar(&columns, "columns", nil, "comma separated columns of the human table after the validator, the checks (e.g: core,rest) or ["+strings.Join(infoColumnNames(), "|")+"], overriding --wide")
+	fs.BoolVar(&wide, "wide", false, "add block heights, version, chain id, peers and backlog to the human table")
+	fs.StringVar(&units, "units", "auto", "unit of the latencies of the human tables [ms|s|auto], auto picking the unit of each value")
+	fs.BoolVar(&showTimings, "timings", false, "add a table splitting latencies between dns, connect, tls and request")
+	fs.StringVar(&sortBy, "sort", "", "sort results [name|latency|failures], configuration order if empty")
+	fs.BoolVar(&sortDesc, "desc", false, "sort results in descending order")
+	fs.StringVar(&outFile, "out", "", "also write the json results to this file or directory, {timestamp} in the name is replaced by the run time")
+}
+
+// addNotifyFlags registers the notifiers which can be enabled without
+// a configuration file
+func addNotifyFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&slackWebhook, "notify-slack", "", "slack incoming webhook url the results are posted to")
+	fs.BoolVar(&notifyDesktop, "notify-desktop", false, "show a desktop notification when checks fail")
+}
+
+// addStoreFlags registers the flags persisting the runs and comparing
+// them with the stored ones
+func addStoreFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&storePath, "store", "", "sqlite database every run is saved to (e.g: results.db)")
+	fs.StringVar(&retention, "retention", "", "delete the stored runs older than this period after each run (e.g: 90d), runs are kept forever if empty")
+	fs.Float64Var(&baselineFactor, "baseline-factor", 0, "report checks slower than this factor times their median latency in the store, disabled if 0")
+	fs.StringVar(&baselineWindow, "baseline-window", "7d", "period of the stored runs the baseline latency is computed over")
+	fs.IntVar(&baselineMinSamples, "baseline-min-samples", 10, "minimum number of stored results required to compare a check with its baseline")
+	fs.BoolVar(&baselineAlert, "baseline-alert", false, "also notify about the checks slower than their baseline")
+}
+
+// addLoopFlags registers the flags of the modes running the checks
+// periodically
+func addLoopFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&interval, "interval", time.Minute, "time between two runs of the checks")
+	fs.IntVar(&historySize, "history-size", 1440, "number of runs kept in memory")
+}
+
+// defaultArgs returns the arguments to execute, running the check
+// command when no subcommand is given as before the subcommands
+func defaultArgs(root *cobra.Command, args []string) []string {
+	if len(args) > 0 {
+		switch args[0] {
+		case "-h", "--help", "--version", "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return args
+		}
+	}
+	if cmd, _, err := root.Find(args); err == nil && cmd != root {
+		return args
+	}
+	return append([]string{"check"}, args...)
+}
+
+// loadConfig returns the network name and the configuration selected
+// by --testnet and --config, registering its external checks, then
+// validating --columns which may name them
+func loadConfig() (string, config) {
+	network, cfg, err := readConfig()
+	if err != nil {
+		fatalConfig("%v", err)
+	}
+	for _, ec := range cfg.ExternalChecks {
+		c, err := newExternalCheck(network, ec)
+		if err != nil {
+			fatalConfig("invalid external check %v: %v", ec.Name, err)
+		}
+		register(c, c.profile)
+	}
+	if err := validateColumns(); err != nil {
+		fatalConfig("%v", err)
+	}
+	return network, cfg
+}
+
+func readConfig() (string, config, error) {
+	var err error
+	var buf, network = mainnetBuf, "mainnet"
+	if testnetConfig {
+		buf, network = testnetBuf, "testnet"
+	}
+	if len(configFile) > 0 {
+		buf, err = os.ReadFile(configFile)
+		if err != nil {
+			return "", config{}, fmt.Errorf("could not read configuration: %w", err)
+		}
+		network = strings.TrimSuffix(filepath.Base(configFile), filepath.Ext(configFile))
+	}
+
+	cfg := config{}
+	err = json.Unmarshal(buf, &cfg)
+	if err != nil {
+		return "", config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if len(cfg.Network) > 0 {
+		network = cfg.Network
+	}
+	return network, cfg, nil
+}
+
+// flagNotifiers returns the configuration with the notifiers set from
+// the command line
+func flagNotifiers(cfg config) config {
+	if len(slackWebhook) > 0 {
+		cfg.Notifiers.Slack = &slackConfig{WebhookURL: slackWebhook}
+	}
+	return cfg
+}
+
+// buildNotifiers returns the notifiers of the configuration and of the
+// command line
+func buildNotifiers(cfg config) []notifier {
+	notifiers, err := flagNotifiers(cfg).Notifiers.build()
+	if err != nil {
+		fatalConfig("invalid configuration: %v", err)
+	}
+	if notifyDesktop {
+		notifiers = append(notifiers, &desktopNotifier{})
+	}
+	return notifiers
+}
+
+// openStoreFlag opens the store of --store, nil if not set, exiting if
+// --retention is not a valid period
+func openStoreFlag() *store {
+	if len(storePath) <= 0 {
+		return nil
+	}
+	if len(retention) > 0 {
+		var err error
+		if retentionPeriod, err = parsePeriod(retention); err != nil || retentionPeriod <= 0 {
+			fatalConfig("invalid retention: %v", retention)
+		}
+	}
+	st, err := openStore(storePath)
+	if err != nil {
+		fatalConfig("could not open store: %v", err)
+	}
+	return st
+}
+
+// validateOutput checks the --output and --sort flags, parsing the
+// template of the template output
+func validateOutput() error {
+	var err error
+	switch output {
+	case "human", "emoji", "json", "ndjson", "checkmk", "tap", "github":
+		break
+	case "template":
+		if len(templateFile) <= 0 {
+			return fmt.Errorf("the template output requires --template")
+		}
+		outputTemplate, err = template.New(filepath.Base(templateFile)).Funcs(templateFuncs).ParseFiles(templateFile)
+		if err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid output format: %v", output)
+	}
+
+	switch units {
+	case "auto", "ms", "s":
+		break
+	default:
+		return fmt.Errorf("invalid units: %v", units)
+	}
+
+	switch sortBy {
+	case "", "name", "latency", "failures":
+		break
+	default:
+		return fmt.Errorf("invalid sort order: %v", sortBy)
+	}
+	return nil
+}
+
+// check runs the checks once, reports the results and exits according
+// to the health policy
+func check() {
+	network, cfg := loadConfig()
+	checks = selectChecks(cfg.Checks)
+	notifiers := buildNotifiers(cfg)
+
+	var previous *report
+	if len(diffFile) > 0 {
+		prev, err := loadReport(diffFile)
+		if err != nil {
+			fatalConfig("could not load previous results: %v", err)
+		}
+		previous = &prev
+	}
+
+	validators := selectValidators(cfg.Validators)
+	if pick && len(only) <= 0 {
+		picked, err := pickValidators(validators)
+		if errors.Is(err, errPickCancelled) {
+			return
+		}
+		if err != nil {
+			fatalConfig("%v", err)
+		}
+		validators = picked
+	}
+	if dryRun {
+		printPlan(validators)
+		return
+	}
+	st := openStoreFlag()
+
+	startedAt := time.Now()
+	runCtx, cancel := runContext()
+	// with --fail-fast the first failure cancels the checks in flight
+	failCtx, abort := context.WithCancelCause(runCtx)
+	// on interrupt the checks in flight are cancelled and the results
+	// collected so far are reported
+	ctx, stop := signal.NotifyContext(failCtx, os.Interrupt, syscall.SIGTERM)
+	onResult := progress(runSize(validators, checks))
+	var firstFailure string
+	if failFast {
+		report := onResult
+		onResult = func(name string, r aPIResult) {
+			report(name, r)
+			if len(r.Error) > 0 && failCtx.Err() == nil {
+				firstFailure = fmt.Sprintf("%v %v: %v", name, r.API, r.Error)
+				abort(errFailFast)
+			}
+		}
+	}
+	res := runChecks(ctx, validators, checks, onResult)
+	interrupted := ctx.Err() != nil && failCtx.Err() == nil
+	aborted := len(firstFailure) > 0
+	stop()
+	abort(nil)
+	cancel()
+	if (interrupted || aborted) && output != "ndjson" && !quiet {
+		// do not print over the progress bar
+		fmt.Println()
+	}
+
+	// any failed check fails the run unless a policy narrows it
+	if !policy.isSet() {
+		policy.failOnAnyError = true
+	}
+
+	sortResults(res, sortBy, sortDesc)
+
+	var changes []diffEntry
+	if previous != nil {
+		changes = diffResults(*previous, res, regressionFactor)
+	}
+	regressions, err := baselineRegressions(st, network, res)
+	if err != nil {
+		slog.Error("could not compare with the baseline", "error", err)
+	}
+	changes = append(changes, regressions...)
+
+	if !quiet {
+		printOutput(network, startedAt, res, changes, interrupted || aborted)
+	}
+
+	// partial results would only notify about the cancelled checks
+	if !interrupted && !aborted {
+		notifyAll(notifiers, notification{
+			Network:   network,
+			Timestamp: startedAt,
+			Results:   res,
+			Changes:   alertedChanges(changes),
+			FirstRun:  previous == nil,
+		})
+		pushMetrics(network, run{Timestamp: startedAt, Results: res})
+	}
+
+	if len(outFile) > 0 {
+		r := newReport(network, startedAt, res, changes)
+		r.Partial = interrupted || aborted
+		if err := writeReport(outputPath(outFile, network, startedAt), r); err != nil {
+			fatalConfig("could not write results: %v", err)
+		}
+	}
+
+	if st != nil {
+		r := newReport(network, startedAt, res, changes)
+		r.Partial = interrupted || aborted
+		_, err := st.saveRun(r)
+		if err == nil {
+			applyRetention(st)
+		}
+		st.close()
+		if err != nil {
+			fatalConfig("could not store results: %v", err)
+		}
+	}
+
+	if interrupted {
+		os.Exit(exitInterrupted)
+	}
+	if aborted {
+		if !quiet {
+			slog.Error("run cancelled on the first failed check", "check", firstFailure)
+		}
+		os.Exit(exitFailed)
+	}
+
+	// every validator down is reported whatever the policy
+	if allDown(res) {
+		if !quiet {
+			slog.Error("every validator is down")
+		}
+		os.Exit(exitAllDown)
+	}
+	if err := policy.check(res); err != nil {
+		if !quiet {
+			slog.Error("health policy failed", "error", err)
+		}
+		os.Exit(exitFailed)
+	}
+}
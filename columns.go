@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// columns are the columns of the human table set by --columns, the
+// checked APIs and with --wide the information columns if empty
+var columns []string
+
+// column is a column of the human table after the validator name
+type column struct {
+	header string
+	// api is set for the latency columns of the checks, rendered by
+	// the cell function of the output
+	api   string
+	value func(v results, resMap map[string]aPIResult) string
+}
+
+// infoColumns are the columns showing the information reported by the
+// checks rather than their latency, those of --wide
+var infoColumns = []struct {
+	name string
+	column
+}{
+	{"core-height", column{header: "core height", value: func(_ results, m map[string]aPIResult) string {
+		return detail(m, "core", detailBlockHeight)
+	}}},
+	{"datanode-height", column{header: "datanode height", value: func(_ results, m map[string]aPIResult) string {
+		return detail(m, "datanode", detailBlockHeight)
+	}}},
+	{"version", column{header: "version", value: func(_ results, m map[string]aPIResult) string {
+		return detail(m, "core", detailVersion)
+	}}},
+	{"chain-id", column{header: "chain id", value: func(_ results, m map[string]aPIResult) string {
+		return detail(m, "core", detailChainID)
+	}}},
+	{"peers", column{header: "peers", value: func(_ results, m map[string]aPIResult) string {
+		if warning := m["core"].Details[detailPeersWarn]; len(warning) > 0 {
+			return color.New(color.FgYellow).Sprint(detail(m, "core", detailPeers) + ", " + warning)
+		}
+		return detail(m, "core", detailPeers)
+	}}},
+	{"backlog", column{header: "backlog", value: func(_ results, m map[string]aPIResult) string {
+		return detail(m, "core", detailBacklog)
+	}}},
+	{"cdn", column{header: "cdn", value: func(v results, _ map[string]aPIResult) string {
+		return cdns(v.APIResults)
+	}}},
+	{"country", column{header: "country", value: func(v results, _ map[string]aPIResult) string {
+		country, _ := location(v.APIResults)
+		return country
+	}}},
+	{"asn", column{header: "asn", value: func(v results, _ map[string]aPIResult) string {
+		_, asn := location(v.APIResults)
+		return asn
+	}}},
+}
+
+func infoColumnNames() []string {
+	names := make([]string, 0, len(infoColumns))
+	for _, c := range infoColumns {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// validateColumns checks the names of --columns are checks or
+// information columns
+func validateColumns() error {
+	for _, name := range columns {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if checkIndex(name) < 0 && infoColumnIndex(name) < 0 {
+			return fmt.Errorf("invalid column: %v (available: the names of the checks, %v)", name, strings.Join(infoColumnNames(), ", "))
+		}
+	}
+	return nil
+}
+
+func infoColumnIndex(name string) int {
+	for i, c := range infoColumns {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// tableColumns returns the columns of the human table, those of
+// --columns in their order if set
+func tableColumns(apis []string) []column {
+	var cols []column
+	if len(columns) <= 0 {
+		for _, api := range apis {
+			cols = append(cols, apiColumn(api))
+		}
+		if wide {
+			for _, c := range infoColumns {
+				if (c.name == "country" || c.name == "asn") && len(geoIPDBs) <= 0 {
+					continue
+				}
+				cols = append(cols, c.column)
+			}
+		}
+		return cols
+	}
+
+	for _, name := range columns {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if i := infoColumnIndex(name); i >= 0 {
+			cols = append(cols, infoColumns[i].column)
+			continue
+		}
+		cols = append(cols, apiColumn(name))
+	}
+	return cols
+}
+
+// apiColumn is the latency column of a check
+func apiColumn(api string) column {
+	return column{header: apiHeader(api), api: api}
+}
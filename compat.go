@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	dnapipb "code.vegaprotocol.io/vega/protos/data-node/api/v2"
+	apipb "code.vegaprotocol.io/vega/protos/vega/api/v1"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func init() {
+	register(apiCheck{"compat", func(v validator) string { return v.GRPC }, checkCompat}, profileStandard)
+}
+
+const (
+	detailCoreVersion     = "core_version"
+	detailCoreCommit      = "core_commit"
+	detailDatanodeVersion = "datanode_version"
+	detailDatanodeCommit  = "datanode_commit"
+	// detailNetworkVersion is set on the compat checks of the
+	// validators running a core other than the network version
+	detailNetworkVersion = "network_version"
+)
+
+// checkCompat compares the version of the data-node with the one of
+// the core it ingests the events of, mismatched pairs serving subtly
+// inconsistent data
+func checkCompat(ctx context.Context, address string) (timings, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, grpcCheckTimeout())
+	defer cancel()
+
+	connection, _, release, err := grpcConnection(ctx, address)
+	if err != nil {
+		return timings{}, nil, err
+	}
+	defer release()
+
+	var t timings
+	reqCtx, cancelReq := requestContext(ctx)
+	defer cancelReq()
+	now := time.Now()
+	stats, err := apipb.NewCoreServiceClient(connection).Statistics(grpcMetadata(reqCtx), &apipb.StatisticsRequest{})
+	if err != nil {
+		t.Request = time.Since(now)
+		return t, nil, fmt.Errorf("could not get the core version: %w", err)
+	}
+	info, err := dnapipb.NewTradingDataServiceClient(connection).Info(grpcMetadata(reqCtx), &dnapipb.InfoRequest{})
+	t.Request = time.Since(now)
+	if err != nil {
+		return t, nil, fmt.Errorf("could not get the data-node version: %w", err)
+	}
+
+	details := map[string]string{
+		detailCoreVersion:     stats.GetStatistics().GetAppVersion(),
+		detailCoreCommit:      stats.GetStatistics().GetAppVersionHash(),
+		detailDatanodeVersion: info.GetVersion(),
+		detailDatanodeCommit:  info.GetCommitHash(),
+	}
+	if details[detailCoreVersion] != details[detailDatanodeVersion] {
+		return t, details, fmt.Errorf("data-node %v paired with core %v", orDash(details[detailDatanodeVersion]), orDash(details[detailCoreVersion]))
+	}
+	return t, details, nil
+}
+
+// networkVersion returns the core version run by most of the
+// validators, the protocol version of the network
+func networkVersion(results []results) string {
+	counts := map[string]int{}
+	for _, v := range results {
+		for _, vr := range v.APIResults {
+			if vr.API == "compat" && len(vr.Details[detailCoreVersion]) > 0 {
+				counts[vr.Details[detailCoreVersion]]++
+			}
+		}
+	}
+	versions := make([]string, 0, len(counts))
+	for v := range counts {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if counts[versions[i]] != counts[versions[j]] {
+			return counts[versions[i]] > counts[versions[j]]
+		}
+		return versions[i] > versions[j]
+	})
+	if len(versions) <= 0 {
+		return ""
+	}
+	return versions[0]
+}
+
+// checkNetworkVersion fails the compat checks of the validators
+// running a core other than the network version, known once all of
+// them are run, so that policies, outputs and notifiers report them
+func checkNetworkVersion(results []results) {
+	network := networkVersion(results)
+	for _, v := range results {
+		for i, vr := range v.APIResults {
+			core := vr.Details[detailCoreVersion]
+			if vr.API != "compat" || len(vr.Error) > 0 || len(core) <= 0 || core == network {
+				continue
+			}
+			vr.Details[detailNetworkVersion] = network
+			v.APIResults[i].Error = fmt.Sprintf("core %v does not match the network version %v", core, network)
+		}
+	}
+}
+
+// renderCompat lists the validators running a core other than the
+// network version or a data-node other than their core version, empty
+// if all of them are compatible
+func renderCompat(results []results) string {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	network := networkVersion(results)
+	t := table.NewWriter()
+	t.SetTitle("versions, network running " + orDash(network))
+	t.AppendHeader(table.Row{"validator", "core", "datanode", "issue"})
+	for _, v := range results {
+		for _, vr := range v.APIResults {
+			core, dn := vr.Details[detailCoreVersion], vr.Details[detailDatanodeVersion]
+			if vr.API != "compat" || len(core) <= 0 {
+				continue
+			}
+			switch {
+			case core != dn:
+				t.AppendRow(table.Row{v.Name, core, red(orDash(dn)), red("data-node does not match its core")})
+			case core != network:
+				t.AppendRow(table.Row{v.Name, yellow(core), dn, yellow("core does not match the network")})
+			}
+		}
+	}
+	if t.Length() <= 0 {
+		return ""
+	}
+	return t.Render()
+}
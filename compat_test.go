@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+// TestCheckNetworkVersion checks a validator running a core other than
+// the one of most validators fails its compat check
+func TestCheckNetworkVersion(t *testing.T) {
+	compat := func(name, version string) results {
+		details := map[string]string{detailCoreVersion: version, detailDatanodeVersion: version}
+		return results{Name: name, APIResults: []aPIResult{{API: "compat", Details: details}}}
+	}
+	res := []results{compat("a", "v0.75.0"), compat("b", "v0.75.0"), compat("c", "v0.74.2")}
+	checkNetworkVersion(res)
+
+	for _, v := range res[:2] {
+		if err := v.APIResults[0].Error; len(err) > 0 {
+			t.Errorf("%v failed: %v", v.Name, err)
+		}
+	}
+	c := res[2].APIResults[0]
+	if len(c.Error) <= 0 {
+		t.Fatal("mismatched core version not reported as an error")
+	}
+	if c.Details[detailNetworkVersion] != "v0.75.0" {
+		t.Errorf("network version detail is %q", c.Details[detailNetworkVersion])
+	}
+}
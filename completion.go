@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion [bash|zsh|fish]",
+		Short: "Generate the shell completion script",
+		Long: `Generate the shell completion script, e.g:
+
+  source <(check_validator_setup completion bash)
+  check_validator_setup completion zsh > "${fpath[1]}/_check_validator_setup"
+  check_validator_setup completion fish > ~/.config/fish/completions/check_validator_setup.fish`,
+		ValidArgs:             []string{"bash", "zsh", "fish"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			case "fish":
+				return root.GenFishCompletion(os.Stdout, true)
+			}
+			return fmt.Errorf("unsupported shell: %v", args[0])
+		},
+	}
+}
+
+// completeValidators completes --only with the names of the validators
+// of the configuration selected by --testnet and --config
+func completeValidators(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	_, cfg, err := readConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := make([]string, 0, len(cfg.Validators))
+	for _, v := range cfg.Validators {
+		names = append(names, v.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeChecks completes --checks with the names of the registered
+// checks
+func completeChecks(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return checkNames(), cobra.ShellCompDirectiveNoFileComp
+}
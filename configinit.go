@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	initOutput string
+	initForce  bool
+)
+
+func newConfigInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a configuration file from the validators names and endpoints asked interactively",
+		Long: `Write a configuration file from the validators names and endpoints asked
+interactively.
+
+The names of the validators can be listed from the data-node of a
+discovery node, their endpoints being then asked for each of them.`,
+		Args: cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			if _, err := os.Stat(initOutput); err == nil && !initForce {
+				fatalConfig("%v already exists, use --force to overwrite it", initOutput)
+			}
+			cfg, err := configWizard(newPrompter(os.Stdin, os.Stdout))
+			if err != nil {
+				fatalConfig("%v", err)
+			}
+			buf, err := json.MarshalIndent(cfg, "", "    ")
+			if err != nil {
+				fatalConfig("could not encode configuration: %v", err)
+			}
+			if err := os.WriteFile(initOutput, append(buf, '\n'), 0o644); err != nil {
+				fatalConfig("could not write configuration: %v", err)
+			}
+			fmt.Printf("\nwrote %v with %d validators, check them with:\n  check_validator_setup --config %v\n",
+				initOutput, len(cfg.Validators), initOutput)
+		},
+	}
+	cmd.Flags().StringVarP(&initOutput, "out", "o", "config.json", "configuration file to write")
+	cmd.Flags().BoolVar(&initForce, "force", false, "overwrite the configuration file if it exists")
+	return cmd
+}
+
+// prompter asks questions on a terminal, or reads the answers from a
+// pipe when scripted
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask returns the answer to the question, def if empty, until it is
+// accepted by validate
+func (p *prompter) ask(question, def string, validate func(string) error) (string, error) {
+	for {
+		if len(def) > 0 {
+			fmt.Fprintf(p.out, "%v [%v]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%v: ", question)
+		}
+		line, err := p.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || len(line) <= 0) {
+			if errors.Is(err, io.EOF) {
+				return "", errors.New("configuration aborted")
+			}
+			return "", err
+		}
+		answer := strings.TrimSpace(line)
+		if len(answer) <= 0 {
+			answer = def
+		}
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// confirm asks a yes or no question
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	d := "y/N"
+	if def {
+		d = "Y/n"
+	}
+	answer, err := p.ask(question+" ("+d+")", "", func(s string) error {
+		switch strings.ToLower(s) {
+		case "", "y", "yes", "n", "no":
+			return nil
+		}
+		return errors.New("answer y or n")
+	})
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return def, nil
+}
+
+// configWizard builds a configuration from the answers of the user
+func configWizard(p *prompter) (config, error) {
+	cfg := config{Validators: []validator{}}
+
+	def := strings.TrimSuffix(filepath.Base(initOutput), filepath.Ext(initOutput))
+	network, err := p.ask("network name", def, nil)
+	if err != nil {
+		return cfg, err
+	}
+	if network != def {
+		cfg.Network = network
+	}
+
+	discovery, err := p.ask("rest url of a discovery data-node listing the validators (empty to enter them by hand)", "", optional(validateHTTPURL))
+	if err != nil {
+		return cfg, err
+	}
+
+	if len(discovery) > 0 {
+		names, err := discoverNames(discovery)
+		if err != nil {
+			return cfg, fmt.Errorf("could not list the validators: %w", err)
+		}
+		fmt.Fprintf(p.out, "%d validators found, the endpoints left empty are not checked\n", len(names))
+		for _, name := range names {
+			fmt.Fprintln(p.out)
+			include, err := p.confirm("add "+name, true)
+			if err != nil {
+				return cfg, err
+			}
+			if !include {
+				continue
+			}
+			v, err := askEndpoints(p, validator{Name: name})
+			if err != nil {
+				return cfg, err
+			}
+			cfg.Validators = append(cfg.Validators, v)
+		}
+	}
+
+	for {
+		fmt.Fprintln(p.out)
+		name, err := p.ask("name of a validator to add (empty to finish)", "", func(s string) error {
+			for _, v := range cfg.Validators {
+				if namedBy(v, s) {
+					return fmt.Errorf("%v is already configured", s)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return cfg, err
+		}
+		if len(name) <= 0 {
+			break
+		}
+		v, err := askEndpoints(p, validator{Name: name})
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Validators = append(cfg.Validators, v)
+	}
+
+	if len(cfg.Validators) <= 0 {
+		return cfg, errors.New("no validator configured")
+	}
+	return cfg, nil
+}
+
+// askEndpoints asks the endpoints of a validator
+func askEndpoints(p *prompter, v validator) (validator, error) {
+	var err error
+	if v.GRPC, err = p.ask("  grpc address (host:port, tls://host:port for tls)", "", optional(validateGRPCAddress)); err != nil {
+		return v, err
+	}
+	if v.REST, err = p.ask("  rest url", "", optional(validateHTTPURL)); err != nil {
+		return v, err
+	}
+	if v.GQL, err = p.ask("  graphql url", "", optional(validateHTTPURL)); err != nil {
+		return v, err
+	}
+	if v.Tendermint, err = p.ask("  tendermint rpc url (optional)", "", optional(validateHTTPURL)); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// optional accepts empty answers
+func optional(validate func(string) error) func(string) error {
+	return func(s string) error {
+		if len(s) <= 0 {
+			return nil
+		}
+		return validate(s)
+	}
+}
+
+func validateHTTPURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) <= 0 {
+		return errors.New("not an http or https url")
+	}
+	return nil
+}
+
+func validateGRPCAddress(s string) error {
+	if _, _, err := net.SplitHostPort(strings.TrimPrefix(s, "tls://")); err != nil {
+		return errors.New("not a host:port address")
+	}
+	return nil
+}
+
+// discoverNames lists the names of the nodes of the network known by
+// the data-node
+func discoverNames(address string) ([]string, error) {
+	var nodes networkNodes
+	if _, _, err := getJSONTimeout(context.Background(), 10*time.Second, address, "api/v2/nodes", nil, &nodes); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range nodes.Nodes.Edges {
+		if len(e.Node.Name) > 0 {
+			names = append(names, e.Node.Name)
+		}
+	}
+	if len(names) <= 0 {
+		return nil, errors.New("no named node")
+	}
+	return names, nil
+}
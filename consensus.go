@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	register(apiCheck{"tendermint", func(v validator) string { return v.Tendermint }, checkTendermint}, profileDeep)
+}
+
+const detailCatchingUp = "catching_up"
+
+var maxHeightLag uint64
+
+// tendermintStatus is the part of the response of the CometBFT /status
+// rpc describing the sync state of the node
+type tendermintStatus struct {
+	Result struct {
+		SyncInfo struct {
+			LatestBlockHeight string `json:"latest_block_height"`
+			CatchingUp        bool   `json:"catching_up"`
+		} `json:"sync_info"`
+		ValidatorInfo struct {
+			Address string `json:"address"`
+			PubKey  struct {
+				Value string `json:"value"`
+			} `json:"pub_key"`
+			VotingPower string `json:"voting_power"`
+		} `json:"validator_info"`
+	} `json:"result"`
+}
+
+// tendermintRPC queries the method of the CometBFT rpc at address,
+// decoding the response in out
+func tendermintRPC(ctx context.Context, address, method string, query url.Values, out any) (timings, map[string]string, error) {
+	if len(address) <= 0 {
+		return timings{}, nil, errors.New("no tendermint rpc configured")
+	}
+	return getJSON(ctx, address, method, query, out)
+}
+
+// checkTendermint checks the CometBFT rpc of the node, reporting its
+// height and whether it is catching up
+func checkTendermint(ctx context.Context, address string) (timings, map[string]string, error) {
+	if len(address) <= 0 {
+		return notConfigured()
+	}
+	var status tendermintStatus
+	t, details, err := tendermintRPC(ctx, address, "status", nil, &status)
+	if err != nil {
+		return t, details, err
+	}
+	details[detailBlockHeight] = status.Result.SyncInfo.LatestBlockHeight
+	details[detailCatchingUp] = strconv.FormatBool(status.Result.SyncInfo.CatchingUp)
+	return t, details, nil
+}
+
+func newConsensusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "consensus",
+		Short: "Show the heights and sync state of every validator side by side, highlighting the outliers",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			_, cfg := loadConfig()
+			consensus(selectValidators(cfg.Validators))
+		},
+	}
+	addRunFlags(cmd)
+	// the checks are those giving the heights of the validators
+	_ = cmd.Flags().MarkHidden("checks")
+	_ = cmd.Flags().MarkHidden("profile")
+	_ = cmd.Flags().MarkHidden("api")
+	cmd.Flags().Uint64Var(&maxHeightLag, "max-height-lag", 10, "number of blocks away from the median height above which a validator is an outlier")
+	return cmd
+}
+
+// consensus checks the core and tendermint apis of the validators and
+// prints their heights
+func consensus(validators []validator) {
+	names := []string{"core"}
+	for _, v := range validators {
+		if len(v.Tendermint) > 0 {
+			names = append(names, "tendermint")
+			break
+		}
+	}
+	checks := selectChecks(names)
+
+	ctx, cancel := runContext()
+	defer cancel()
+	res := runChecks(ctx, validators, checks, progress(runSize(validators, checks)))
+	fmt.Println(renderConsensus(validators, res))
+}
+
+// renderConsensus renders the heights of each validator, the distance
+// to the median core height and their sync state, outliers in red and
+// validators catching up in yellow
+func renderConsensus(validators []validator, res []results) string {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	type row struct {
+		core, tendermint       map[string]string
+		coreErr, tendermintErr string
+		coreHeight, tmHeight   uint64
+		hasCore, hasTendermint bool
+	}
+	rows := make([]row, len(res))
+	var heights []uint64
+	for i, v := range res {
+		for _, r := range v.APIResults {
+			switch r.API {
+			case "core":
+				rows[i].core, rows[i].coreErr = r.Details, r.Error
+				if h, err := strconv.ParseUint(r.Details[detailBlockHeight], 10, 64); err == nil && len(r.Error) <= 0 {
+					rows[i].coreHeight, rows[i].hasCore = h, true
+					heights = append(heights, h)
+				}
+			case "tendermint":
+				rows[i].tendermint, rows[i].tendermintErr = r.Details, r.Error
+				if h, err := strconv.ParseUint(r.Details[detailBlockHeight], 10, 64); err == nil && len(r.Error) <= 0 {
+					rows[i].tmHeight, rows[i].hasTendermint = h, true
+				}
+			}
+		}
+	}
+
+	var median uint64
+	title := "consensus view, no core height"
+	if len(heights) > 0 {
+		sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+		median = heights[len(heights)/2]
+		title = fmt.Sprintf("consensus view, median core height %d", median)
+	}
+
+	t := table.NewWriter()
+	t.SetTitle(title)
+	t.AppendHeader(table.Row{"validator", "core height", "lag", "chain status", "tendermint height", "catching up", "error"})
+	for i, v := range res {
+		r := rows[i]
+		coreHeight, lag, status := "-", "-", orDash(r.core[detailChainStatus])
+		if r.hasCore {
+			coreHeight = strconv.FormatUint(r.coreHeight, 10)
+			d := int64(median) - int64(r.coreHeight)
+			lag = strconv.FormatInt(d, 10)
+			if d > int64(maxHeightLag) || -d > int64(maxHeightLag) {
+				coreHeight, lag = red(coreHeight), red(lag)
+			}
+		}
+		if status != "-" && status != "connected" {
+			status = yellow(status)
+		}
+
+		tmHeight, catchingUp := "-", "-"
+		if r.hasTendermint {
+			tmHeight = strconv.FormatUint(r.tmHeight, 10)
+			if r.hasCore && (r.tmHeight > r.coreHeight+maxHeightLag || r.coreHeight > r.tmHeight+maxHeightLag) {
+				tmHeight = yellow(tmHeight)
+			}
+			catchingUp = r.tendermint[detailCatchingUp]
+			if catchingUp == "true" {
+				catchingUp = yellow(catchingUp)
+			}
+		}
+
+		errStr := r.coreErr
+		if len(errStr) <= 0 && len(validators[i].Tendermint) > 0 {
+			errStr = r.tendermintErr
+		}
+		t.AppendRow(table.Row{v.Name, coreHeight, lag, status, tmHeight, catchingUp, errStr})
+	}
+	return t.Render()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// TestDeepProfileWithoutTendermint runs the checks of the deep profile
+// probing the optional endpoints on a validator configured like those
+// of the embedded configurations, without them, along with a failing
+// rest check
+func TestDeepProfileWithoutTendermint(t *testing.T) {
+	optional := map[string]bool{"tendermint": true, "signing": true, "votingpower": true, "corerest": true}
+	var selected []checker
+	for _, c := range profileChecks(profileDeep) {
+		if optional[c.name()] || c.name() == "rest" {
+			selected = append(selected, c)
+			delete(optional, c.name())
+		}
+	}
+	if len(optional) > 0 {
+		t.Fatalf("checks missing from the deep profile: %v", optional)
+	}
+
+	v := validator{Name: "p2p", GRPC: "127.0.0.1:1", REST: "http://127.0.0.1:1", GQL: "http://127.0.0.1:1/graphql"}
+	res := runChecks(context.Background(), []validator{v}, selected, nil)
+	if len(res[0].APIResults) != 1 || res[0].APIResults[0].API != "rest" {
+		t.Fatalf("results %+v, expected the rest check only", res[0].APIResults)
+	}
+	// the checks not configured do not count as healthy
+	if status := res[0].status(); status != "down" {
+		t.Errorf("validator is %v, expected down", status)
+	}
+}
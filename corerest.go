@@ -0,0 +1,35 @@
+package main
+
+import "context"
+
+func init() {
+	register(apiCheck{"corerest", func(v validator) string { return v.CoreREST }, checkCoreREST}, profileDeep)
+}
+
+// coreStatistics is the part of the response of the /statistics
+// endpoint of the core rest gateway
+type coreStatistics struct {
+	Statistics struct {
+		BlockHeight string `json:"blockHeight"`
+		AppVersion  string `json:"appVersion"`
+		ChainID     string `json:"chainId"`
+	} `json:"statistics"`
+}
+
+// checkCoreREST checks the rest gateway of the core node, a service
+// of its own distinct from the data-node rest api
+func checkCoreREST(ctx context.Context, address string) (timings, map[string]string, error) {
+	if len(address) <= 0 {
+		return notConfigured()
+	}
+
+	var stats coreStatistics
+	t, details, err := getJSON(ctx, address, "statistics", nil, &stats)
+	if err != nil {
+		return t, details, err
+	}
+	details[detailBlockHeight] = stats.Statistics.BlockHeight
+	details[detailVersion] = stats.Statistics.AppVersion
+	details[detailChainID] = stats.Statistics.ChainID
+	return t, details, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleConfig runs the checks of a profile on a cron schedule in
+// server mode, e.g: {"profile": "deep", "cron": "0 * * * *"}
+type scheduleConfig struct {
+	Profile string `json:"profile"`
+	Cron    string `json:"cron"`
+}
+
+// schedule is a profile run on a cron schedule
+type schedule struct {
+	profile profile
+	cron    *cronSpec
+	checks  []checker
+}
+
+func buildSchedules(cfgs []scheduleConfig) ([]schedule, error) {
+	var schedules []schedule
+	for _, c := range cfgs {
+		p, err := parseProfile(c.Profile)
+		if err != nil {
+			return nil, err
+		}
+		spec, err := parseCron(c.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression for profile %v: %w", p, err)
+		}
+		schedules = append(schedules, schedule{profile: p, cron: spec, checks: profileChecks(p)})
+	}
+	return schedules, nil
+}
+
+// cronSpec is a parsed 5 fields cron expression, each field being the
+// bitset of the matching values
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	// the day matches on either the day of month or the day of week if
+	// both are restricted, on the restricted one otherwise
+	domAny, dowAny bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses a standard cron expression (minute, hour, day of
+// month, month, day of week) supporting *, lists, ranges and steps, or
+// one of the @hourly like descriptors
+func parseCron(expr string) (*cronSpec, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := cronDescriptors[expr]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	var (
+		spec cronSpec
+		err  error
+	)
+	bounds := []struct {
+		set      *uint64
+		min, max int
+	}{
+		{&spec.minute, 0, 59},
+		{&spec.hour, 0, 23},
+		{&spec.dom, 1, 31},
+		{&spec.month, 1, 12},
+		{&spec.dow, 0, 7},
+	}
+	for i, b := range bounds {
+		if *b.set, err = parseCronField(fields[i], b.min, b.max); err != nil {
+			return nil, fmt.Errorf("field %q: %w", fields[i], err)
+		}
+	}
+	// 7 is sunday too
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+	spec.domAny = fields[2] == "*"
+	spec.dowAny = fields[4] == "*"
+	return &spec, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			step = s
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", bounds[1])
+			}
+		default:
+			v, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo, hi = v, v
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("out of range %d-%d", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (s *cronSpec) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<t.Weekday()) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// next returns the first time strictly after t matching the schedule,
+// the zero time if none does within the next 5 years
+func (s *cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case s.month&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// seriesBuckets is the number of points of the charts of the dashboard
+const seriesBuckets = 120
+
+//go:embed web
+var webFS embed.FS
+
+// registerDashboard serves the web dashboard on /, a single page
+// polling /api/v1/results, and the history it charts:
+//
+//	/api/v1/series?since=24h&validator=NAME  stored latency and
+//	                                         availability per check
+func registerDashboard(mux *http.ServeMux, network string, st *store) {
+	assets, err := fs.Sub(webFS, "web")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("/", http.FileServer(http.FS(assets)))
+
+	mux.HandleFunc("/api/v1/series", func(w http.ResponseWriter, r *http.Request) {
+		if st == nil {
+			http.Error(w, "no history, the server was started without --store", http.StatusNotFound)
+			return
+		}
+		period := r.URL.Query().Get("since")
+		if len(period) <= 0 {
+			period = "24h"
+		}
+		since, err := parseSince(period)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err := st.results(network, since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if name := r.URL.Query().Get("validator"); len(name) > 0 {
+			filtered := res[:0:0]
+			for _, sr := range res {
+				if strings.EqualFold(sr.Validator, name) {
+					filtered = append(filtered, sr)
+				}
+			}
+			res = filtered
+		}
+		writeJSON(w, checkSeries(res, since, time.Now(), seriesBuckets))
+	})
+}
+
+// series is the history of a check of a validator
+type series struct {
+	Validator string        `json:"validator"`
+	API       string        `json:"api"`
+	Points    []seriesPoint `json:"points"`
+}
+
+// seriesPoint aggregates the stored results of a check over a period,
+// AvgMS being null if all of them failed
+type seriesPoint struct {
+	Time         time.Time `json:"time"`
+	Checks       int       `json:"checks"`
+	Availability float64   `json:"availability"`
+	AvgMS        *float64  `json:"avg_ms"`
+}
+
+// checkSeries aggregates the results per validator and API in buckets
+// splitting the period, the empty buckets and the partial runs being
+// left out
+func checkSeries(res []storedResult, from, to time.Time, buckets int) []series {
+	step := to.Sub(from) / time.Duration(buckets)
+	if step <= 0 {
+		step = time.Second
+	}
+
+	type bucket struct {
+		checks, failures int
+		totalMS          float64
+	}
+	index := map[string]int{}
+	var out []series
+	var agg []map[int]*bucket
+	for _, r := range res {
+		if r.Partial {
+			continue
+		}
+		key := r.Validator + "/" + r.API
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, series{Validator: r.Validator, API: r.API})
+			agg = append(agg, map[int]*bucket{})
+		}
+		n := min(max(int(r.StartedAt.Sub(from)/step), 0), buckets-1)
+		b, ok := agg[i][n]
+		if !ok {
+			b = &bucket{}
+			agg[i][n] = b
+		}
+		b.checks++
+		if r.Status == apiStatusError {
+			b.failures++
+			continue
+		}
+		b.totalMS += r.LatencyMS
+	}
+
+	for i := range out {
+		out[i].Points = []seriesPoint{}
+		for n := 0; n < buckets; n++ {
+			b, ok := agg[i][n]
+			if !ok {
+				continue
+			}
+			p := seriesPoint{
+				Time:         from.Add(time.Duration(n) * step).UTC(),
+				Checks:       b.checks,
+				Availability: 100 * float64(b.checks-b.failures) / float64(b.checks),
+			}
+			if succeeded := b.checks - b.failures; succeeded > 0 {
+				avg := b.totalMS / float64(succeeded)
+				p.AvgMS = &avg
+			}
+			out[i].Points = append(out[i].Points, p)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Validator != out[j].Validator {
+			return out[i].Validator < out[j].Validator
+		}
+		return checkIndex(out[i].API) < checkIndex(out[j].API)
+	})
+	return out
+}
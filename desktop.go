@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// desktopNotifier shows a native desktop notification when a run has
+// failures
+type desktopNotifier struct{}
+
+func (d *desktopNotifier) name() string { return "desktop" }
+
+func (d *desktopNotifier) notify(ctx context.Context, n notification) error {
+	alerts := n.alerts()
+	if !n.hasFailures() && len(alerts) <= 0 {
+		return nil
+	}
+
+	failed := []string{}
+	for _, v := range n.Results {
+		for _, vr := range v.APIResults {
+			if len(vr.Error) > 0 {
+				failed = append(failed, v.Name+" "+vr.API)
+			}
+		}
+	}
+	title := fmt.Sprintf("vega %v: %d checks failed", n.Network, len(failed))
+	body := strings.Join(failed, ", ")
+	if len(alerts) > 0 {
+		title = fmt.Sprintf("vega %v: %v", n.Network, alerts[0])
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+		script := fmt.Sprintf(`display notification "%v" with title "%v"`, quote.Replace(body), quote.Replace(title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "windows":
+		quote := strings.NewReplacer(`'`, `''`)
+		script := fmt.Sprintf(`[void][System.Reflection.Assembly]::LoadWithPartialName('System.Windows.Forms');`+
+			`$n = New-Object System.Windows.Forms.NotifyIcon;`+
+			`$n.Icon = [System.Drawing.SystemIcons]::Warning;`+
+			`$n.Visible = $true;`+
+			`$n.ShowBalloonTip(10000, '%v', '%v', 'Warning');`+
+			`Start-Sleep -Seconds 1`, quote.Replace(title), quote.Replace(body))
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", script)
+	default:
+		cmd = exec.CommandContext(ctx, "notify-send", "--urgency=critical", title, body)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	changeFailed    = "failed"
+	changeRecovered = "recovered"
+	changeRegressed = "regressed"
+	// changeBaseline is slower than the median latency of the stored
+	// runs rather than than the previous run
+	changeBaseline = "slower than baseline"
+)
+
+// diffEntry is a change of a validator API between a previous run and
+// the current one
+type diffEntry struct {
+	Name     string  `json:"name"`
+	API      string  `json:"api"`
+	Change   string  `json:"change"`
+	BeforeMS float64 `json:"before_ms"`
+	AfterMS  float64 `json:"after_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// loadReport reads a report previously written by the json output
+func loadReport(path string) (report, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return report{}, err
+	}
+
+	r := report{}
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return report{}, err
+	}
+	if r.SchemaVersion != jsonSchemaVersion {
+		return report{}, fmt.Errorf("unsupported json schema version %v, expected %v", r.SchemaVersion, jsonSchemaVersion)
+	}
+
+	return r, nil
+}
+
+// diffResults lists the APIs which failed, recovered or got slower by
+// more than regressionFactor since the previous run
+func diffResults(prev report, res []results, regressionFactor float64) []diffEntry {
+	before := map[string]apiReport{}
+	for _, v := range prev.Validators {
+		for _, vr := range v.APIResults {
+			before[v.Name+"/"+vr.API] = vr
+		}
+	}
+
+	changes := []diffEntry{}
+	for _, v := range res {
+		for _, vr := range v.APIResults {
+			old, ok := before[v.Name+"/"+vr.API]
+			if !ok {
+				continue
+			}
+
+			entry := diffEntry{
+				Name:     v.Name,
+				API:      vr.API,
+				BeforeMS: old.TimeTakenMS,
+				AfterMS:  toMS(vr.TimeTaken),
+				Error:    vr.Error,
+			}
+			failed := len(vr.Error) > 0
+			switch {
+			case failed && old.Status != apiStatusError:
+				entry.Change = changeFailed
+			case !failed && old.Status == apiStatusError:
+				entry.Change = changeRecovered
+			case !failed && entry.AfterMS > old.TimeTakenMS*regressionFactor:
+				entry.Change = changeRegressed
+			default:
+				continue
+			}
+			changes = append(changes, entry)
+		}
+	}
+
+	return changes
+}
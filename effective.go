@@ -0,0 +1,175 @@
+package main
+
+import (
+	"maps"
+	"os"
+)
+
+const redacted = "<redacted>"
+
+// effectiveConfig is the configuration a run would use once the
+// embedded or file configuration is merged with the environment and the
+// command line, secrets redacted
+type effectiveConfig struct {
+	Network string `json:"network"`
+	// Source is the configuration file, or the embedded network
+	Source         string                `json:"source"`
+	Validators     []validator           `json:"validators"`
+	Checks         []string              `json:"checks"`
+	ExternalChecks []externalCheckConfig `json:"external_checks,omitempty"`
+	Schedules      []scheduleConfig      `json:"schedules,omitempty"`
+	Notifiers      notifiersConfig       `json:"notifiers"`
+	DesktopNotify  bool                  `json:"desktop_notifications,omitempty"`
+	Run            effectiveRun          `json:"run"`
+	Transport      effectiveTransport    `json:"transport"`
+	OTLP           *effectiveOTLP        `json:"otlp,omitempty"`
+}
+
+type effectiveRun struct {
+	Timeout        string `json:"timeout"`
+	GRPCTimeout    string `json:"grpc_timeout"`
+	HTTPTimeout    string `json:"http_timeout"`
+	HeavyTimeout   string `json:"heavy_timeout"`
+	MaxDuration    string `json:"max_duration,omitempty"`
+	Concurrency    int    `json:"concurrency"`
+	Shuffle        bool   `json:"shuffle,omitempty"`
+	Samples        int    `json:"samples"`
+	Retries        int    `json:"retries"`
+	RetryBackoff   string `json:"retry_backoff"`
+	WarnThresholds string `json:"warn_thresholds"`
+	CritThresholds string `json:"crit_thresholds"`
+	Precheck       bool   `json:"precheck,omitempty"`
+}
+
+type effectiveTransport struct {
+	CAFile             string `json:"ca_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	Resolver           string `json:"resolver,omitempty"`
+	SourceIP           string `json:"source_ip,omitempty"`
+	IPFamily           string `json:"ip_family,omitempty"`
+	ConnectTimeout     string `json:"connect_timeout,omitempty"`
+	RequestTimeout     string `json:"request_timeout,omitempty"`
+}
+
+type effectiveOTLP struct {
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// newEffectiveConfig resolves the configuration of the network as
+// modified by the flags and the environment
+func newEffectiveConfig(network string, cfg config) effectiveConfig {
+	source := "embedded " + network
+	if len(configFile) > 0 {
+		source = configFile
+	}
+
+	ec := effectiveConfig{
+		Network:        network,
+		Source:         source,
+		Checks:         []string{},
+		ExternalChecks: cfg.ExternalChecks,
+		Schedules:      cfg.Schedules,
+		Notifiers:      redactNotifiers(flagNotifiers(cfg).Notifiers),
+		DesktopNotify:  notifyDesktop,
+		Run: effectiveRun{
+			Timeout:        timeout.String(),
+			GRPCTimeout:    grpcCheckTimeout().String(),
+			HTTPTimeout:    httpCheckTimeout().String(),
+			HeavyTimeout:   heavyTimeout.String(),
+			Concurrency:    concurrency,
+			Shuffle:        shuffle,
+			Samples:        samples,
+			Retries:        retries,
+			RetryBackoff:   retryBackoff.String(),
+			WarnThresholds: warnThresholds.String(),
+			CritThresholds: critThresholds.String(),
+			Precheck:       precheck,
+		},
+		Transport: effectiveTransport{
+			CAFile:             caFile,
+			InsecureSkipVerify: insecureSkipVerify,
+			Resolver:           resolverAddr,
+			SourceIP:           sourceIP,
+			IPFamily:           ipFamily(),
+		},
+	}
+	if maxDuration > 0 {
+		ec.Run.MaxDuration = maxDuration.String()
+	}
+	if connectTimeout > 0 {
+		ec.Transport.ConnectTimeout = connectTimeout.String()
+	}
+	if requestTimeout > 0 {
+		ec.Transport.RequestTimeout = requestTimeout.String()
+	}
+	if len(ec.Transport.IPFamily) > 0 {
+		ec.Transport.IPFamily = "ipv" + ec.Transport.IPFamily
+	}
+
+	for _, v := range selectValidators(cfg.Validators) {
+		v.Headers = redactValues(v.Headers)
+		ec.Validators = append(ec.Validators, v)
+	}
+	for _, c := range selectChecks(cfg.Checks) {
+		ec.Checks = append(ec.Checks, c.name())
+	}
+
+	endpoint := otlpEndpoint
+	if len(endpoint) <= 0 {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if len(endpoint) > 0 {
+		ec.OTLP = &effectiveOTLP{Endpoint: endpoint, Headers: redactValues(otlpHeaders)}
+	}
+	return ec
+}
+
+// redactNotifiers returns a copy of the notifiers without their
+// credentials
+func redactNotifiers(n notifiersConfig) notifiersConfig {
+	if n.Slack != nil {
+		s := *n.Slack
+		s.WebhookURL = redacted
+		n.Slack = &s
+	}
+	if n.Telegram != nil {
+		t := *n.Telegram
+		t.BotToken = redacted
+		n.Telegram = &t
+	}
+	if n.Opsgenie != nil {
+		o := *n.Opsgenie
+		o.APIKey = redacted
+		n.Opsgenie = &o
+	}
+	if n.Email != nil {
+		e := *n.Email
+		if len(e.Password) > 0 {
+			e.Password = redacted
+		}
+		n.Email = &e
+	}
+	webhooks := make([]webhookConfig, 0, len(n.Webhooks))
+	for _, w := range n.Webhooks {
+		// the token of most webhooks is part of their url
+		w.URL = redacted
+		w.Headers = redactValues(w.Headers)
+		webhooks = append(webhooks, w)
+	}
+	n.Webhooks = webhooks
+	return n
+}
+
+// redactValues returns a copy of the headers with their values
+// redacted, as they may hold api keys
+func redactValues(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	out := maps.Clone(headers)
+	for k := range out {
+		out[k] = redacted
+	}
+	return out
+}
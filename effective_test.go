@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestRedactNotifiers checks no credential of the notifiers is left in
+// the effective configuration, nor redacted in the configuration used
+func TestRedactNotifiers(t *testing.T) {
+	secrets := []string{"slack-secret", "telegram-secret", "opsgenie-secret", "email-secret", "webhook-secret", "header-secret"}
+	n := notifiersConfig{
+		Slack:    &slackConfig{WebhookURL: "https://hooks.slack.com/services/slack-secret"},
+		Telegram: &telegramConfig{BotToken: "telegram-secret"},
+		Opsgenie: &opsgenieConfig{APIKey: "opsgenie-secret"},
+		Email:    &emailConfig{Host: "smtp.example.com", Password: "email-secret"},
+		Webhooks: []webhookConfig{{
+			URL:     "https://example.com/hooks/webhook-secret?token=webhook-secret",
+			Headers: map[string]string{"Authorization": "Bearer header-secret"},
+		}},
+	}
+
+	buf, err := json.Marshal(redactNotifiers(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range secrets {
+		if strings.Contains(string(buf), s) {
+			t.Errorf("%v not redacted: %s", s, buf)
+		}
+	}
+	if n.Webhooks[0].URL != "https://example.com/hooks/webhook-secret?token=webhook-secret" || n.Webhooks[0].Headers["Authorization"] != "Bearer header-secret" {
+		t.Errorf("configuration used modified: %+v", n.Webhooks[0])
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type emailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// OnlyChanges only sends an email when the status of an API
+	// changed since the previous run, instead of after every run with
+	// failures
+	OnlyChanges bool `json:"only_changes"`
+}
+
+// emailNotifier sends a digest of the failures over smtp
+type emailNotifier struct {
+	cfg emailConfig
+}
+
+func (e *emailNotifier) name() string { return "email" }
+
+func (e *emailNotifier) notify(ctx context.Context, n notification) error {
+	alerts := n.alerts()
+	if len(n.Changes) <= 0 && len(alerts) <= 0 && (e.cfg.OnlyChanges || !n.hasFailures()) {
+		return nil
+	}
+
+	var failed int
+	for _, v := range n.Results {
+		failed += v.failures()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %v\r\n", e.cfg.From)
+	fmt.Fprintf(&b, "To: %v\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: [vega %v] %d validator api checks failing\r\n", n.Network, failed)
+	fmt.Fprintf(&b, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&b, "Run of %v\r\n", n.Timestamp.UTC().Format(time.RFC3339))
+	if len(alerts) > 0 {
+		b.WriteString("\r\nNetwork alerts:\r\n")
+		for _, a := range alerts {
+			fmt.Fprintf(&b, "  %v\r\n", a)
+		}
+	}
+	if len(n.Changes) > 0 {
+		b.WriteString("\r\nChanges since previous run:\r\n")
+		for _, c := range n.Changes {
+			fmt.Fprintf(&b, "  %v %v %v (%.0fms -> %.0fms) %v\r\n", c.Name, c.API, c.Change, c.BeforeMS, c.AfterMS, c.Error)
+		}
+	}
+	if n.hasFailures() {
+		b.WriteString("\r\nFailures:\r\n")
+		for _, v := range n.Results {
+			for _, vr := range v.APIResults {
+				if len(vr.Error) > 0 {
+					fmt.Fprintf(&b, "  %v %v: %v\r\n", v.Name, vr.API, vr.Error)
+				}
+			}
+		}
+	}
+
+	port := e.cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if len(e.cfg.Username) > 0 {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(port))
+	return sendMail(ctx, addr, e.cfg.Host, auth, e.cfg.From, e.cfg.To, []byte(b.String()))
+}
+
+// sendMail is smtp.SendMail bounded by the context, the whole exchange
+// failing once its deadline, or notifyTimeout, is reached
+func sendMail(ctx context.Context, addr, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(notifyTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	// the deadline only covers the blocked reads and writes, a
+	// cancellation closes the connection
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestEmailNotifyUnresponsiveServer checks the notification gives up
+// at the deadline of the context on a server which never answers
+func TestEmailNotifyUnresponsiveServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	e := &emailNotifier{cfg: emailConfig{Host: host, Port: p, From: "from@example.com", To: []string{"to@example.com"}}}
+	n := notification{
+		Network: "testnet",
+		Results: []results{{Name: "v", APIResults: []aPIResult{{API: "rest", Error: "down"}}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := e.notify(ctx, n); err == nil {
+		t.Fatal("notify succeeded on a server which never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("notify returned after %v", elapsed)
+	}
+}
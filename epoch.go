@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func init() {
+	register(archivalCheck{}, profileDeep)
+	warnThresholds.setDefault("archival", heavyWarnThreshold)
+	critThresholds.setDefault("archival", heavyCritThreshold)
+}
+
+const (
+	detailArchival   = "archival"
+	detailEpochStart = "epoch_start"
+)
+
+var archivalEpoch uint64
+
+// networkEpoch is the part of the response of the data-node
+// /api/v2/epoch endpoint
+type networkEpoch struct {
+	Epoch struct {
+		Seq        string `json:"seq"`
+		Timestamps struct {
+			StartTime string `json:"startTime"`
+		} `json:"timestamps"`
+	} `json:"epoch"`
+}
+
+// archivalCheck verifies the data-nodes configured as archival can
+// still be queried for --archival-epoch, timing the query. It passes
+// on the other data-nodes without querying them.
+type archivalCheck struct{}
+
+func (archivalCheck) name() string {
+	return "archival"
+}
+
+func (archivalCheck) endpoint(v validator) string {
+	return v.REST
+}
+
+func (archivalCheck) run(ctx context.Context, v validator) (timings, map[string]string, error) {
+	if !v.Archival {
+		return timings{}, map[string]string{detailArchival: "false"}, nil
+	}
+
+	id := strconv.FormatUint(archivalEpoch, 10)
+	var epoch networkEpoch
+	t, details, err := getJSONTimeout(ctx, heavyTimeout, v.REST, "api/v2/epoch", url.Values{"id": {id}}, &epoch)
+	if details != nil {
+		details[detailArchival] = "true"
+	}
+	if err != nil {
+		return t, details, fmt.Errorf("epoch %v not available: %w", id, err)
+	}
+	if epoch.Epoch.Seq != id {
+		return t, details, fmt.Errorf("epoch %v not available: got epoch %v", id, orDash(epoch.Epoch.Seq))
+	}
+	if ns, err := strconv.ParseInt(epoch.Epoch.Timestamps.StartTime, 10, 64); err == nil {
+		details[detailEpochStart] = time.Unix(0, ns).UTC().Format(time.RFC3339)
+	}
+	return t, details, nil
+}
+
+const (
+	detailEpoch       = "epoch"
+	detailEpochExpiry = "epoch_expiry"
+)
+
+// epochGrace is how long past its expiry time an epoch can last before
+// it is reported as stuck
+var epochGrace time.Duration
+
+// networkAlerts returns the problems of the network as a whole found
+// in the results of a run, rather than of any validator
+func networkAlerts(res []results) []string {
+	var alerts []string
+	if alert := stuckEpoch(res, time.Now()); len(alert) > 0 {
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// stuckEpoch returns an alert if the expiry time of the current epoch,
+// as reported by the most advanced core node, is more than
+// --epoch-grace in the past, empty otherwise. Nodes lagging behind
+// report past epochs and are ignored.
+func stuckEpoch(res []results, now time.Time) string {
+	var (
+		epoch  uint64
+		expiry time.Time
+		found  bool
+	)
+	for _, v := range res {
+		for _, vr := range v.APIResults {
+			if vr.API != "core" || len(vr.Error) > 0 {
+				continue
+			}
+			seq, err := strconv.ParseUint(vr.Details[detailEpoch], 10, 64)
+			if err != nil {
+				continue
+			}
+			exp, err := time.Parse(time.RFC3339Nano, vr.Details[detailEpochExpiry])
+			if err != nil {
+				continue
+			}
+			if !found || seq > epoch || (seq == epoch && exp.After(expiry)) {
+				epoch, expiry, found = seq, exp, true
+			}
+		}
+	}
+	if !found || now.Sub(expiry) <= epochGrace {
+		return ""
+	}
+	return fmt.Sprintf("epoch %d is stuck, it should have ended %v ago", epoch, now.Sub(expiry).Truncate(time.Second))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// externalCheckConfig declares a check implemented by a command run
+// once per validator
+type externalCheckConfig struct {
+	Name    string   `json:"name"`
+	Command []string `json:"command"`
+	// Timeout of the command (e.g: 10s), defaults to --timeout
+	Timeout string `json:"timeout"`
+	// Profile is the lightest profile running the check
+	// [quick|standard|deep], defaults to standard
+	Profile string `json:"profile"`
+}
+
+// externalOutput is the json printed by the command on its standard
+// output, a non zero exit status fails the check too
+type externalOutput struct {
+	Error   string            `json:"error"`
+	Details map[string]string `json:"details"`
+	// LatencyMS overrides the latency reported for the check, which is
+	// the run time of the command by default
+	LatencyMS *float64 `json:"latency_ms"`
+}
+
+// externalCheck runs a command with the validator endpoints in its
+// environment and reports the result it prints
+type externalCheck struct {
+	cfg     externalCheckConfig
+	network string
+	timeout time.Duration
+	profile profile
+}
+
+func newExternalCheck(network string, cfg externalCheckConfig) (*externalCheck, error) {
+	if len(cfg.Name) <= 0 {
+		return nil, errors.New("missing name")
+	}
+	if len(cfg.Command) <= 0 {
+		return nil, errors.New("missing command")
+	}
+	c := &externalCheck{cfg: cfg, network: network, profile: profileStandard}
+	if len(cfg.Timeout) > 0 {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		c.timeout = d
+	}
+	if len(cfg.Profile) > 0 {
+		p, err := parseProfile(cfg.Profile)
+		if err != nil {
+			return nil, err
+		}
+		c.profile = p
+	}
+	c.cfg.Name = strings.ToLower(cfg.Name)
+	return c, nil
+}
+
+func (c *externalCheck) name() string { return c.cfg.Name }
+
+func (c *externalCheck) endpoint(validator) string { return strings.Join(c.cfg.Command, " ") }
+
+func (c *externalCheck) run(ctx context.Context, v validator) (timings, map[string]string, error) {
+	d := c.timeout
+	if d <= 0 {
+		d = timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.cfg.Command[0], c.cfg.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"VEGA_NETWORK="+c.network,
+		"VEGA_VALIDATOR_NAME="+v.Name,
+		"VEGA_VALIDATOR_GRPC="+v.GRPC,
+		"VEGA_VALIDATOR_REST="+v.REST,
+		"VEGA_VALIDATOR_GQL="+v.GQL,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	t := timings{Request: time.Since(start)}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+			return t, nil, fmt.Errorf("%w: %v", err, msg)
+		}
+		return t, nil, err
+	}
+
+	var out externalOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return t, nil, fmt.Errorf("invalid output: %w", err)
+	}
+	if out.LatencyMS != nil {
+		t.Request = fromMS(*out.LatencyMS)
+	}
+	if len(out.Error) > 0 {
+		return t, out.Details, errors.New(out.Error)
+	}
+	return t, out.Details, nil
+}
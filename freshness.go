@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func init() {
+	register(apiCheck{"freshness", func(v validator) string { return v.REST }, checkFreshness}, profileStandard)
+}
+
+const detailDataAge = "data_age"
+
+var maxDataAge time.Duration
+
+// vegaTime is the response of the data-node /api/v2/vega/time
+// endpoint, the timestamp of the latest block it processed
+type vegaTime struct {
+	Timestamp string `json:"timestamp"`
+}
+
+// checkFreshness checks the data-node still ingests events, failing if
+// the newest block it processed is older than --max-data-age
+func checkFreshness(ctx context.Context, address string) (timings, map[string]string, error) {
+	var vt vegaTime
+	t, details, err := getJSON(ctx, address, "api/v2/vega/time", nil, &vt)
+	if err != nil {
+		return t, details, err
+	}
+	ns, err := strconv.ParseInt(vt.Timestamp, 10, 64)
+	if err != nil {
+		return t, details, fmt.Errorf("invalid block timestamp: %q", vt.Timestamp)
+	}
+
+	age := time.Since(time.Unix(0, ns)).Truncate(time.Second)
+	details[detailDataAge] = age.String()
+	if age > maxDataAge {
+		return t, details, fmt.Errorf("stale data: newest block is %v old", age)
+	}
+	return t, details, nil
+}
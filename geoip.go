@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/spf13/pflag"
+)
+
+const (
+	detailCountry = "country"
+	detailASN     = "asn"
+)
+
+var (
+	geoIPFiles []string
+	geoIPDBs   []*maxminddb.Reader
+)
+
+// geoRecord holds the fields of the GeoIP2/GeoLite2 country, city and
+// ASN databases the endpoints are enriched with
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+func addGeoIPFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVar(&geoIPFiles, "geoip-db", nil, "MaxMind mmdb files (e.g: GeoLite2-Country.mmdb,GeoLite2-ASN.mmdb) locating the endpoints, shown in the wide output")
+}
+
+// setupGeoIP opens the GeoIP databases
+func setupGeoIP() error {
+	for _, f := range geoIPFiles {
+		db, err := maxminddb.Open(f)
+		if err != nil {
+			return fmt.Errorf("could not open geoip database: %w", err)
+		}
+		geoIPDBs = append(geoIPDBs, db)
+	}
+	return nil
+}
+
+// addGeoDetails adds the country and ASN of the ip the check connected
+// to, if found in the databases
+func addGeoDetails(details map[string]string) {
+	ip := net.ParseIP(details[detailIP])
+	if len(geoIPDBs) <= 0 || ip == nil {
+		return
+	}
+
+	// the record is filled by each database in turn
+	var rec geoRecord
+	for _, db := range geoIPDBs {
+		_ = db.Lookup(ip, &rec)
+	}
+	if len(rec.Country.ISOCode) > 0 {
+		details[detailCountry] = rec.Country.ISOCode
+	}
+	if rec.ASN > 0 {
+		details[detailASN] = "AS" + strconv.FormatUint(uint64(rec.ASN), 10) + " " + rec.ASOrg
+	}
+}
+
+// location returns the country and ASN of the first API of a validator
+// located, - if none is
+func location(res []aPIResult) (string, string) {
+	for _, r := range res {
+		if _, ok := r.Details[detailCountry]; ok {
+			return r.Details[detailCountry], orDash(r.Details[detailASN])
+		}
+		if asn, ok := r.Details[detailASN]; ok {
+			return "-", asn
+		}
+	}
+	return "-", "-"
+}
+
+func orDash(s string) string {
+	if len(s) <= 0 {
+		return "-"
+	}
+	return s
+}
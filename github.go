@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// printGitHub writes the results as github actions workflow commands,
+// an error or warning annotation per failing or slow check, and adds
+// a markdown table of the results to the job summary
+func printGitHub(r report, res []results) {
+	if r.Partial {
+		fmt.Println("::warning title=partial results::the run was interrupted before all the checks completed")
+	}
+	for _, alert := range r.Alerts {
+		fmt.Printf("::error title=network alert::%v\n", escapeWorkflowData(alert))
+	}
+	for _, v := range res {
+		for _, vr := range v.APIResults {
+			title := escapeWorkflowProperty(v.Name + " " + apiHeader(vr.API))
+			switch apiStatus(vr) {
+			case apiStatusError:
+				fmt.Printf("::error title=%v::%v\n", title, escapeWorkflowData(vr.Error))
+			case apiStatusWarning, apiStatusCritical:
+				fmt.Printf("::warning title=%v::%v responded in %v, above the %v threshold of %v\n",
+					title, apiHeader(vr.API), formatDuration(vr.TimeTaken), apiStatus(vr), thresholdOf(vr))
+			}
+		}
+	}
+
+	// the summary is printed when not run by github actions, so that
+	// it can be previewed
+	var w io.Writer = os.Stdout
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); len(path) > 0 {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			fatalConfig("could not open job summary: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if _, err := io.WriteString(w, githubSummary(r.Network, res)); err != nil {
+		fatalConfig("could not write job summary: %v", err)
+	}
+}
+
+// thresholdOf returns the latency threshold a slow result went over
+func thresholdOf(res aPIResult) string {
+	if apiStatus(res) == apiStatusCritical {
+		return formatDuration(critThresholds.get(res.API))
+	}
+	return formatDuration(warnThresholds.get(res.API))
+}
+
+// githubSummary renders the results as a markdown table followed by
+// the errors
+func githubSummary(network string, res []results) string {
+	apis := resultAPIs(res)
+	counts := map[string]int{}
+	for _, v := range res {
+		counts[v.status()]++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %v validators: %d up, %d degraded, %d down\n\n",
+		network, counts["up"], counts["degraded"], counts["down"])
+
+	b.WriteString("| validator | status |")
+	for _, api := range apis {
+		fmt.Fprintf(&b, " %v |", apiHeader(api))
+	}
+	b.WriteString("\n|---|---|" + strings.Repeat("---|", len(apis)) + "\n")
+	for _, v := range res {
+		byAPI := map[string]aPIResult{}
+		for _, vr := range v.APIResults {
+			byAPI[vr.API] = vr
+		}
+		fmt.Fprintf(&b, "| %v | %v |", escapeMarkdownCell(v.Name), v.status())
+		for _, api := range apis {
+			vr, ok := byAPI[api]
+			if !ok {
+				b.WriteString(" - |")
+				continue
+			}
+			switch apiStatus(vr) {
+			case apiStatusOK:
+				fmt.Fprintf(&b, " ✅ %v |", formatDuration(vr.TimeTaken))
+			case apiStatusError:
+				b.WriteString(" ❌ |")
+			default:
+				fmt.Fprintf(&b, " ⚠️ %v |", formatDuration(vr.TimeTaken))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	var errors strings.Builder
+	for _, v := range res {
+		for _, vr := range v.APIResults {
+			if len(vr.Error) > 0 {
+				fmt.Fprintf(&errors, "| %v | %v | %v |\n",
+					escapeMarkdownCell(v.Name), apiHeader(vr.API), escapeMarkdownCell(vr.Error))
+			}
+		}
+	}
+	if errors.Len() > 0 {
+		b.WriteString("\n<details><summary>errors</summary>\n\n| validator | api | error |\n|---|---|---|\n")
+		b.WriteString(errors.String())
+		b.WriteString("\n</details>\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// escapeWorkflowData escapes the message of a workflow command
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeWorkflowProperty escapes a property of a workflow command,
+// such as its title
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	register(apiCheck{"gqlcomplexity", func(v validator) string { return v.GQL }, checkGQLComplexity}, profileDeep)
+}
+
+const detailComplexityLimit = "complexity_limit"
+
+// gqlNestedQuery is a moderately nested query any consistently
+// configured graphql gateway is expected to accept
+const gqlNestedQuery = `{
+  markets(pagination: {first: 10}) {
+    edges {
+      node {
+        id
+        state
+        tradableInstrument {
+          instrument {
+            name
+            product {
+              ... on Future {
+                settlementAsset { id symbol decimals }
+              }
+            }
+          }
+        }
+        data { markPrice bestBidPrice bestOfferPrice openInterest }
+      }
+    }
+  }
+}`
+
+// complexityLimit extracts the limit from the error of gqlgen servers
+// rejecting a query too complex
+var complexityLimit = regexp.MustCompile(`exceeds the limit of (\d+)`)
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// checkGQLComplexity sends a nested query to the graphql api, failing
+// if the node rejects it because of its complexity limit
+func checkGQLComplexity(ctx context.Context, address string) (timings, map[string]string, error) {
+	payload, err := json.Marshal(map[string]string{"query": gqlNestedQuery})
+	if err != nil {
+		return timings{}, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, httpCheckTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(payload))
+	if err != nil {
+		return timings{}, nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	// gqlgen answers queries failing validation, such as exceeding
+	// the complexity limit, with 422
+	var resp gqlResponse
+	t, details, err := doHTTPStatus(req, &resp, http.StatusOK, http.StatusUnprocessableEntity)
+	if err != nil {
+		return t, details, err
+	}
+	for _, e := range resp.Errors {
+		if e.Extensions.Code == "COMPLEXITY_LIMIT_EXCEEDED" || strings.Contains(e.Message, "complexity") {
+			if m := complexityLimit.FindStringSubmatch(e.Message); m != nil {
+				details[detailComplexityLimit] = m[1]
+			}
+			return t, details, fmt.Errorf("query rejected by the complexity limit: %v", e.Message)
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return t, details, errors.New(resp.Errors[0].Message)
+	}
+	if len(resp.Data) <= 0 || string(resp.Data) == "null" {
+		return t, details, errors.New("empty graphql response")
+	}
+	return t, details, nil
+}
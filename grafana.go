@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Endpoints compatible with the Grafana JSON datasource
+// (/grafana/metrics, /grafana/query...) and plain JSON documents for
+// the Infinity datasource (/grafana/status, /grafana/latency).
+
+const (
+	grafanaTargetLatency = "latency"
+	grafanaTargetStatus  = "status"
+)
+
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+type grafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []grafanaColumn `json:"columns"`
+	Rows    [][]any         `json:"rows"`
+}
+
+// grafanaRow is a flat entry of the Infinity datasource documents
+type grafanaRow struct {
+	Timestamp time.Time `json:"timestamp"`
+	Validator string    `json:"validator"`
+	API       string    `json:"api"`
+	Status    string    `json:"status"`
+	LatencyMS float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func registerGrafana(mux *http.ServeMux, history *runHistory) {
+	// used by the datasource connection test
+	mux.HandleFunc("/grafana/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/grafana/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	targets := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/grafana/metrics" {
+			writeJSON(w, []map[string]string{
+				{"label": grafanaTargetLatency, "value": grafanaTargetLatency},
+				{"label": grafanaTargetStatus, "value": grafanaTargetStatus},
+			})
+			return
+		}
+		writeJSON(w, []string{grafanaTargetLatency, grafanaTargetStatus})
+	}
+	mux.HandleFunc("/grafana/search", targets)
+	mux.HandleFunc("/grafana/metrics", targets)
+
+	mux.HandleFunc("/grafana/query", func(w http.ResponseWriter, r *http.Request) {
+		q := grafanaQuery{}
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		out := []any{}
+		for _, t := range q.Targets {
+			switch t.Target {
+			case grafanaTargetLatency:
+				for _, ts := range grafanaLatency(history.all(), q.Range.From, q.Range.To) {
+					out = append(out, ts)
+				}
+			case grafanaTargetStatus:
+				out = append(out, grafanaStatus(history))
+			}
+		}
+		writeJSON(w, out)
+	})
+
+	mux.HandleFunc("/grafana/status", func(w http.ResponseWriter, r *http.Request) {
+		rows := []grafanaRow{}
+		if last, ok := history.latest(); ok {
+			rows = grafanaRows(last)
+		}
+		writeJSON(w, rows)
+	})
+
+	mux.HandleFunc("/grafana/latency", func(w http.ResponseWriter, r *http.Request) {
+		rows := []grafanaRow{}
+		for _, run := range history.all() {
+			rows = append(rows, grafanaRows(run)...)
+		}
+		writeJSON(w, rows)
+	})
+}
+
+// grafanaLatency returns one series per validator API with the latency
+// of the successful checks within the time range
+func grafanaLatency(runs []run, from, to time.Time) []*grafanaSeries {
+	series := []*grafanaSeries{}
+	byTarget := map[string]*grafanaSeries{}
+
+	for _, run := range runs {
+		if (!from.IsZero() && run.Timestamp.Before(from)) || (!to.IsZero() && run.Timestamp.After(to)) {
+			continue
+		}
+		for _, v := range run.Results {
+			for _, vr := range v.APIResults {
+				if len(vr.Error) > 0 {
+					continue
+				}
+				target := v.Name + " " + vr.API
+				ts, ok := byTarget[target]
+				if !ok {
+					ts = &grafanaSeries{Target: target, Datapoints: [][2]float64{}}
+					byTarget[target] = ts
+					series = append(series, ts)
+				}
+				ts.Datapoints = append(ts.Datapoints, [2]float64{
+					toMS(vr.TimeTaken),
+					float64(run.Timestamp.UnixMilli()),
+				})
+			}
+		}
+	}
+
+	return series
+}
+
+// grafanaStatus returns the latest run as a table
+func grafanaStatus(history *runHistory) grafanaTable {
+	t := grafanaTable{
+		Type: "table",
+		Columns: []grafanaColumn{
+			{Text: "validator", Type: "string"},
+			{Text: "api", Type: "string"},
+			{Text: "status", Type: "string"},
+			{Text: "latency_ms", Type: "number"},
+			{Text: "error", Type: "string"},
+		},
+		Rows: [][]any{},
+	}
+
+	if last, ok := history.latest(); ok {
+		for _, row := range grafanaRows(last) {
+			t.Rows = append(t.Rows, []any{row.Validator, row.API, row.Status, row.LatencyMS, row.Error})
+		}
+	}
+
+	return t
+}
+
+func grafanaRows(r run) []grafanaRow {
+	rows := []grafanaRow{}
+	for _, v := range r.Results {
+		for _, vr := range v.APIResults {
+			rows = append(rows, grafanaRow{
+				Timestamp: r.Timestamp.UTC(),
+				Validator: v.Name,
+				API:       vr.API,
+				Status:    apiStatus(vr),
+				LatencyMS: toMS(vr.TimeTaken),
+				Error:     vr.Error,
+			})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// heavyChecks query the data-node endpoints which are the first to
+// fall over under load, they are opt-in and have their own latency
+// thresholds
+var heavyChecks = []struct {
+	api   string
+	path  string
+	query url.Values
+}{
+	{"rewards", "api/v2/rewards", nil},
+	{"transfers", "api/v2/transfers", nil},
+	{"ledger", "api/v2/ledgerentry/history", url.Values{"filter.fromAccountFilter.accountTypes": {"ACCOUNT_TYPE_GENERAL"}}},
+}
+
+const (
+	heavyPageSize      = "100"
+	heavyWarnThreshold = 2 * time.Second
+	heavyCritThreshold = 5 * time.Second
+)
+
+var heavyTimeout time.Duration
+
+func init() {
+	for _, c := range heavyChecks {
+		register(apiCheck{c.api, func(v validator) string { return v.REST }, heavyProbe(c.path, c.query)}, profileDeep)
+		warnThresholds.setDefault(c.api, heavyWarnThreshold)
+		critThresholds.setDefault(c.api, heavyCritThreshold)
+	}
+}
+
+// heavyProbe returns a probe querying a full page of the endpoint, the
+// download of the response being part of the latency
+func heavyProbe(path string, query url.Values) func(ctx context.Context, address string) (timings, map[string]string, error) {
+	q := url.Values{"pagination.first": {heavyPageSize}}
+	for k, v := range query {
+		q[k] = v
+	}
+	return func(ctx context.Context, address string) (timings, map[string]string, error) {
+		var page json.RawMessage
+		start := time.Now()
+		t, details, err := getJSONTimeout(ctx, heavyTimeout, address, path, q, &page)
+		if err == nil {
+			t.Request = time.Since(start) - t.DNS - t.Connect - t.TLS
+		}
+		return t, details, err
+	}
+}
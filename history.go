@@ -0,0 +1,435 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySince  string
+	historyFormat string
+	historyLimit  int
+	retention     string
+	olderThan     string
+	exportFormat  string
+	exportSince   string
+
+	// retentionPeriod is --retention parsed by openStoreFlag
+	retentionPeriod time.Duration
+)
+
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Query the runs saved with --store",
+	}
+	cmd.PersistentFlags().StringVar(&storePath, "store", "", "sqlite database the runs were saved to")
+	_ = cmd.MarkPersistentFlagRequired("store")
+
+	report := &cobra.Command{
+		Use:   "report",
+		Short: "Availability and latency percentiles per validator and api",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			historyReport()
+		},
+	}
+	report.Flags().StringVar(&historySince, "since", "30d", "period covered by the report (e.g: 12h, 30d, 2w)")
+	report.Flags().StringVar(&historyFormat, "format", "table", "report format [table|csv]")
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the stored runs older than --older-than",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			historyPrune()
+		},
+	}
+	prune.Flags().StringVar(&olderThan, "older-than", "", "age of the runs to delete (e.g: 90d)")
+	_ = prune.MarkFlagRequired("older-than")
+
+	runs := &cobra.Command{
+		Use:   "runs",
+		Short: "List the most recent stored runs",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			historyRuns()
+		},
+	}
+	runs.Flags().IntVar(&historyLimit, "limit", 20, "number of runs listed")
+
+	diff := &cobra.Command{
+		Use:   "diff <run-a> <run-b>",
+		Short: "Compare the status and latencies of two stored runs",
+		Args:  cobra.ExactArgs(2),
+		Run: func(_ *cobra.Command, args []string) {
+			historyDiff(args[0], args[1])
+		},
+	}
+	diff.Flags().Float64Var(&regressionFactor, "diff-regression-factor", 1.5, "latency increase factor reported as a regression")
+
+	export := &cobra.Command{
+		Use:   "export",
+		Short: "Export the stored results, one row per check",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			historyExport()
+		},
+	}
+	export.Flags().StringVar(&exportSince, "since", "", "only export the runs of this period (e.g: 30d), all of them if empty")
+	export.Flags().StringVar(&exportFormat, "format", "csv", "export format [csv|parquet]")
+	export.Flags().StringVar(&outFile, "out", "", "file the results are exported to, stdout if empty")
+
+	cmd.AddCommand(report, runs, diff, export, prune)
+	return cmd
+}
+
+// parseSince returns the start of the period ending now, the period
+// being a duration also accepting days (d) and weeks (w)
+func parseSince(s string) (time.Time, error) {
+	d, err := parsePeriod(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-d), nil
+}
+
+// parsePeriod parses a duration also accepting days (d) and weeks (w)
+func parsePeriod(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit > 0 {
+		n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid period: %v", s)
+		}
+		return time.Duration(n * float64(unit)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid period: %v", s)
+	}
+	return d, nil
+}
+
+// uptime aggregates the stored results of an API of a validator
+type uptime struct {
+	Validator string
+	API       string
+	Checks    int
+	Failures  int
+	// Latencies of the successful checks
+	Latencies []time.Duration
+}
+
+func (u uptime) availability() float64 {
+	if u.Checks <= 0 {
+		return 0
+	}
+	return 100 * float64(u.Checks-u.Failures) / float64(u.Checks)
+}
+
+// uptimes aggregates the results per validator and API, in validator
+// then registry order, leaving out the partial runs
+func uptimes(res []storedResult) []uptime {
+	index := map[string]int{}
+	var out []uptime
+	for _, r := range res {
+		if r.Partial {
+			continue
+		}
+		key := r.Validator + "/" + r.API
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, uptime{Validator: r.Validator, API: r.API})
+		}
+		out[i].Checks++
+		if r.Status == apiStatusError {
+			out[i].Failures++
+			continue
+		}
+		out[i].Latencies = append(out[i].Latencies, fromMS(r.LatencyMS))
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Validator != out[j].Validator {
+			return out[i].Validator < out[j].Validator
+		}
+		return checkIndex(out[i].API) < checkIndex(out[j].API)
+	})
+	return out
+}
+
+func historyReport() {
+	network, _ := loadConfig()
+	since, err := parseSince(historySince)
+	if err != nil {
+		fatalConfig("%v", err)
+	}
+	if historyFormat != "table" && historyFormat != "csv" {
+		fatalConfig("invalid report format: %v", historyFormat)
+	}
+
+	st, err := openStore(storePath)
+	if err != nil {
+		fatalConfig("could not open store: %v", err)
+	}
+	defer st.close()
+
+	res, err := st.results(network, since)
+	if err != nil {
+		fatalConfig("could not read history: %v", err)
+	}
+	runs := map[int64]bool{}
+	for _, r := range res {
+		if !r.Partial {
+			runs[r.RunID] = true
+		}
+	}
+
+	ups := uptimes(res)
+	if historyFormat == "csv" {
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"validator", "api", "checks", "failures", "availability", "avg_ms", "p50_ms", "p95_ms", "p99_ms"})
+		for _, u := range ups {
+			w.Write([]string{
+				u.Validator, u.API, strconv.Itoa(u.Checks), strconv.Itoa(u.Failures),
+				strconv.FormatFloat(u.availability(), 'f', 3, 64),
+				msString(average(u.Latencies)), msString(percentile(u.Latencies, 50)),
+				msString(percentile(u.Latencies, 95)), msString(percentile(u.Latencies, 99)),
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			fatalConfig("could not write report: %v", err)
+		}
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetTitle(fmt.Sprintf("%v since %v, %d runs", network, since.Format(time.RFC3339), len(runs)))
+	t.AppendHeader(table.Row{"validator", "api", "checks", "availability", "avg", "p50", "p95", "p99"})
+	for _, u := range ups {
+		row := table.Row{u.Validator, apiHeader(u.API), u.Checks, fmt.Sprintf("%.2f%%", u.availability())}
+		if len(u.Latencies) <= 0 {
+			row = append(row, "-", "-", "-", "-")
+		} else {
+			row = append(row, average(u.Latencies), percentile(u.Latencies, 50), percentile(u.Latencies, 95), percentile(u.Latencies, 99))
+		}
+		t.AppendRow(row)
+	}
+	fmt.Println(t.Render())
+}
+
+func historyPrune() {
+	before, err := parseSince(olderThan)
+	if err != nil {
+		fatalConfig("%v", err)
+	}
+
+	st, err := openStore(storePath)
+	if err != nil {
+		fatalConfig("could not open store: %v", err)
+	}
+	defer st.close()
+
+	n, err := st.prune(before)
+	if err != nil {
+		fatalConfig("could not prune history: %v", err)
+	}
+	if err := st.vacuum(); err != nil {
+		fatalConfig("could not vacuum store: %v", err)
+	}
+	fmt.Printf("%d runs older than %v deleted\n", n, before.Format(time.RFC3339))
+}
+
+func historyRuns() {
+	network, _ := loadConfig()
+	st, err := openStore(storePath)
+	if err != nil {
+		fatalConfig("could not open store: %v", err)
+	}
+	defer st.close()
+
+	runs, err := st.runs(network, historyLimit)
+	if err != nil {
+		fatalConfig("could not read history: %v", err)
+	}
+
+	t := table.NewWriter()
+	t.SetTitle(network)
+	t.AppendHeader(table.Row{"id", "started at", "checks", "failures", "partial"})
+	for _, r := range runs {
+		t.AppendRow(table.Row{r.ID, r.StartedAt.Format(time.RFC3339), r.Checks, r.Failures, r.Partial})
+	}
+	fmt.Println(t.Render())
+}
+
+func historyDiff(a, b string) {
+	st, err := openStore(storePath)
+	if err != nil {
+		fatalConfig("could not open store: %v", err)
+	}
+	defer st.close()
+
+	load := func(arg string) (run, string) {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			fatalConfig("invalid run id: %v", arg)
+		}
+		r, network, err := st.loadRun(id)
+		if err != nil {
+			fatalConfig("could not load run: %v", err)
+		}
+		return r, network
+	}
+	runA, network := load(a)
+	runB, _ := load(b)
+
+	before := map[string]results{}
+	for _, v := range runA.Results {
+		before[v.Name] = v
+	}
+
+	t := table.NewWriter()
+	t.SetTitle(fmt.Sprintf("run %v to %v", a, b))
+	t.AppendHeader(table.Row{"validator", "api", "before", "after", "delta"})
+	for _, v := range runB.Results {
+		prev := map[string]aPIResult{}
+		for _, r := range before[v.Name].APIResults {
+			prev[r.API] = r
+		}
+		for _, r := range v.APIResults {
+			row := table.Row{v.Name, apiHeader(r.API)}
+			old, ok := prev[r.API]
+			if !ok {
+				row = append(row, "-", coloredDuration(r), "-")
+			} else {
+				row = append(row, coloredDuration(old), coloredDuration(r), signedDuration(r.TimeTaken-old.TimeTaken))
+			}
+			t.AppendRow(row)
+		}
+	}
+	fmt.Println(t.Render())
+
+	status := table.NewWriter()
+	status.AppendHeader(table.Row{"validator", "before", "after"})
+	for _, v := range runB.Results {
+		prev := "-"
+		if old, ok := before[v.Name]; ok {
+			prev = old.status()
+		}
+		status.AppendRow(table.Row{v.Name, prev, v.status()})
+	}
+	fmt.Println(status.Render())
+
+	changes := diffResults(newReport(network, runA.Timestamp, runA.Results, nil), runB.Results, regressionFactor)
+	if len(changes) > 0 {
+		fmt.Println(renderDiff(changes))
+	}
+}
+
+func historyExport() {
+	network, _ := loadConfig()
+	var since time.Time
+	if len(exportSince) > 0 {
+		var err error
+		if since, err = parseSince(exportSince); err != nil {
+			fatalConfig("%v", err)
+		}
+	}
+	if exportFormat != "csv" && exportFormat != "parquet" {
+		fatalConfig("invalid export format: %v", exportFormat)
+	}
+
+	st, err := openStore(storePath)
+	if err != nil {
+		fatalConfig("could not open store: %v", err)
+	}
+	defer st.close()
+
+	res, err := st.results(network, since)
+	if err != nil {
+		fatalConfig("could not read history: %v", err)
+	}
+
+	w := os.Stdout
+	if len(outFile) > 0 {
+		if w, err = os.Create(outFile); err != nil {
+			fatalConfig("could not create export: %v", err)
+		}
+		defer w.Close()
+	}
+
+	if exportFormat == "parquet" {
+		err = exportParquet(w, network, res)
+	} else {
+		err = exportCSV(w, network, res)
+	}
+	if err != nil {
+		fatalConfig("could not export history: %v", err)
+	}
+}
+
+var exportHeader = []string{
+	"run_id", "started_at", "network", "validator", "api", "status",
+	"latency_ms", "dns_ms", "connect_ms", "tls_ms", "request_ms", "attempts", "error",
+}
+
+func exportCSV(w io.Writer, network string, res []storedResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write(exportHeader)
+	for _, r := range res {
+		cw.Write([]string{
+			strconv.FormatInt(r.RunID, 10), r.StartedAt.UTC().Format(time.RFC3339Nano), network, r.Validator, r.API, r.Status,
+			formatMS(r.LatencyMS), formatMS(r.DNSMS), formatMS(r.ConnectMS), formatMS(r.TLSMS), formatMS(r.RequestMS),
+			strconv.Itoa(r.Attempts), r.Error,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatMS(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 3, 64)
+}
+
+func signedDuration(d time.Duration) string {
+	if d > 0 {
+		return "+" + d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+// applyRetention deletes the stored runs older than --retention if set,
+// the period being validated by openStoreFlag
+func applyRetention(st *store) {
+	if retentionPeriod <= 0 {
+		return
+	}
+	if n, err := st.prune(time.Now().Add(-retentionPeriod)); err != nil {
+		slog.Error("could not prune history", "error", err)
+	} else if n > 0 {
+		slog.Debug("pruned history", "runs", n)
+	}
+}
+
+func msString(d time.Duration) string {
+	return formatMS(toMS(d))
+}
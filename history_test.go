@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestPartialRunsAvailability checks the checks cancelled by an
+// interrupted run do not count as downtime in the history report and
+// the status page
+func TestPartialRunsAvailability(t *testing.T) {
+	st, err := openStore(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.close()
+
+	now := time.Now().UTC()
+	stored := func(at time.Time, partial bool, status, errStr string) report {
+		return report{
+			Network:   "testnet",
+			Timestamp: at,
+			Partial:   partial,
+			Validators: []validatorReport{{
+				Name:       "v",
+				Status:     "up",
+				APIResults: []apiReport{{API: "rest", Status: status, TimeTakenMS: 10, Error: errStr}},
+			}},
+		}
+	}
+	for _, r := range []report{
+		stored(now.Add(-2*time.Minute), false, apiStatusOK, ""),
+		stored(now.Add(-time.Minute), true, apiStatusError, "interrupted: context canceled"),
+	} {
+		if _, err := st.saveRun(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	from := now.AddDate(0, 0, -1).Truncate(24 * time.Hour)
+	res, err := st.results("testnet", from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("%d stored results, expected 2", len(res))
+	}
+
+	ups := uptimes(res)
+	if len(ups) != 1 || ups[0].Checks != 1 || ups[0].availability() != 100 {
+		t.Errorf("history report %+v, expected a single check available", ups)
+	}
+	latest, err := latestCompleteRun(st, "testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := newStatusPage("testnet", res, latest, from, 2)
+	if len(page.Validators) != 1 || page.Validators[0].Availability != 100 {
+		t.Errorf("status page %+v, expected the validator fully available", page.Validators)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+var (
+	logLevel  string
+	logFormat string
+)
+
+// setupLogger installs the logger selected by --log-level and
+// --log-format as the default one, the log package writing through it
+// too
+func setupLogger() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return fmt.Errorf("invalid log level: %v", logLevel)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch logFormat {
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid log format: %v", logFormat)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
@@ -1,30 +1,48 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	_ "embed"
-	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
-	"net/url"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
-	dnapipb "code.vegaprotocol.io/vega/protos/data-node/api/v2"
-	apipb "code.vegaprotocol.io/vega/protos/vega/api/v1"
-
-	"github.com/fatih/color"
-	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/schollz/progressbar/v3"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
+	"golang.org/x/term"
+)
+
+// Exit statuses, letting scripts tell the kinds of failure apart:
+//
+//	0    every check succeeded, or the results met the policy if one is
+//	     set
+//	1    a check failed, or the results broke the policy if one is set
+//	     (--fail-if-down, ...)
+//	2    invalid configuration or command line, or a store or output
+//	     error
+//	3    every validator is down, whether a policy is set or not
+//	130  interrupted, following the shell convention of 128 + SIGINT
+const (
+	exitFailed      = 1
+	exitConfig      = 2
+	exitAllDown     = 3
+	exitInterrupted = 130
 )
 
-const gqlPayload = `{"query": "{epoch{id}}"}`
+// fatalConfig logs the configuration, command line, store or output
+// error and exits with exitConfig
+func fatalConfig(format string, v ...any) {
+	log.Printf(format, v...)
+	os.Exit(exitConfig)
+}
 
 var (
 	//go:embed testnet_config.json
@@ -33,306 +51,464 @@ var (
 	//go:embed mainnet_config.json
 	mainnetBuf []byte
 
-	timeout = 2 * time.Second
+	// version is set at build time using -ldflags "-X main.version=...",
+	// see version.go for the other build metadata
+	version = "dev"
+
+	timeout     time.Duration
+	grpcTimeout time.Duration
+	httpTimeout time.Duration
+	maxDuration time.Duration
+
+	enabledChecks []string
+	profileName   string
+	concurrency   int
+	shuffle       bool
+	retries       int
+	samples       int
+	retryBackoff  time.Duration
 
 	testnetConfig bool
-	only          string
+	configFile    string
+	only          []string
+	exclude       []string
 	output        string
+	quiet         bool
+	dryRun        bool
+	failFast      bool
+	wide          bool
+	noColor       bool
+	noProgress    bool
+	showTimings   bool
+	units         string
+	sortBy        string
+	sortDesc      bool
+	policy        exitPolicy
+
+	outputTemplate *template.Template
+
+	templateFile     string
+	outFile          string
+	diffFile         string
+	storePath        string
+	regressionFactor float64
+
+	slackWebhook  string
+	notifyDesktop bool
+
+	serveAddr   string
+	interval    time.Duration
+	historySize int
 )
 
+type validator struct {
+	Name string `json:"name"`
+	GRPC string `json:"grpc"`
+	REST string `json:"rest"`
+	GQL  string `json:"gql"`
+	// CoreREST is the url of the rest gateway of the core node, the
+	// transaction submission path, optional
+	CoreREST string `json:"core_rest,omitempty"`
+	// Headers are sent with the rest and gql requests and as grpc
+	// metadata, Host overriding the host or authority of the requests
+	Headers map[string]string `json:"headers,omitempty"`
+	// ServerName overrides the name the certificates are verified
+	// against, for endpoints dialed by ip or through a load balancer
+	ServerName string `json:"server_name,omitempty"`
+	// Tendermint is the url of the CometBFT rpc of the node, optional
+	Tendermint string `json:"tendermint,omitempty"`
+	// Tags are free form labels of the validator, e.g: its operator
+	// or hosting provider
+	Tags []string `json:"tags,omitempty"`
+	// Archival is set when the data-node claims to keep the whole
+	// history of the network
+	Archival bool `json:"archival,omitempty"`
+}
+
 type config struct {
-	Validators []struct {
-		Name string `json:"name"`
-		GRPC string `json:"grpc"`
-		REST string `json:"rest"`
-		GQL  string `json:"gql"`
-	} `json:"validators"`
+	// Network is the name of the network, defaults to the name of the
+	// embedded configuration or of the configuration file
+	Network    string      `json:"network,omitempty"`
+	Validators []validator `json:"validators"`
+	// Checks lists the checks to run, those of the standard profile if
+	// empty
+	Checks []string `json:"checks,omitempty"`
+	// ExternalChecks are run in addition to the built-in checks
+	ExternalChecks []externalCheckConfig `json:"external_checks,omitempty"`
+	// Schedules replace --interval in server mode
+	Schedules []scheduleConfig `json:"schedules,omitempty"`
+	Notifiers notifiersConfig  `json:"notifiers"`
 }
 
 type aPIResult struct {
-	API       string        `json:"api"`
-	TimeTaken time.Duration `json:"time_taken"`
-	Error     string        `json:"error"`
+	API       string
+	TimeTaken time.Duration
+	// Timings splits TimeTaken between connection establishment and
+	// the request itself
+	Timings timings
+	Error   string
+	// Details holds information reported by the node, e.g: its
+	// block height or version
+	Details map[string]string
+	// Attempts is the number of times the check ran, more than one
+	// if it was retried
+	Attempts int
+	// Samples holds the latency statistics when the check is sampled
+	// multiple times, TimeTaken is then the average
+	Samples *sampleStats
 }
 
-type results struct {
-	Name       string      `json:"name"`
-	APIResults []aPIResult `json:"api_results"`
+type sampleStats struct {
+	Count  int
+	Failed int
+	Min    time.Duration
+	Avg    time.Duration
+	Max    time.Duration
+	P95    time.Duration
 }
 
-func init() {
-	flag.BoolVar(&testnetConfig, "testnet", false, "check testnet")
-	flag.StringVar(&only, "only", "", "check a single validator")
-	flag.StringVar(&output, "output", "human", "results output [human|json]")
+type results struct {
+	Name       string
+	APIResults []aPIResult
 }
 
-func main() {
-	flag.Parse()
-	var buf = mainnetBuf
-	if testnetConfig {
-		buf = testnetBuf
+// failures returns the number of failed checks
+func (r results) failures() int {
+	var failed int
+	for _, v := range r.APIResults {
+		if len(v.Error) > 0 {
+			failed++
+		}
 	}
-	if len(only) > 0 {
-		only = strings.ToLower(only)
+	return failed
+}
+
+// totalTime returns the time taken by all the checks
+func (r results) totalTime() time.Duration {
+	var total time.Duration
+	for _, v := range r.APIResults {
+		total += v.TimeTaken
 	}
+	return total
+}
 
-	var isJsonOutput bool
-	switch output {
-	case "human":
-		break
-	case "json":
-		isJsonOutput = true
+// status summarises the health of a validator across all its APIs
+func (r results) status() string {
+	var failed, slow int
+	for _, v := range r.APIResults {
+		switch apiStatus(v) {
+		case apiStatusError:
+			failed++
+		case apiStatusWarning, apiStatusCritical:
+			slow++
+		}
+	}
+	switch {
+	case failed == 0 && slow == 0:
+		return "up"
+	case failed == len(r.APIResults):
+		return "down"
 	default:
-		log.Fatalf("invalid output format: %v", output)
+		return "degraded"
 	}
+}
 
-	cfg := config{}
-	err := json.Unmarshal(buf, &cfg)
-	if err != nil {
-		log.Fatalf("invalid configuration: %v", err)
+func main() {
+	root := newRootCmd()
+	root.SetArgs(defaultArgs(root, os.Args[1:]))
+	if err := root.Execute(); err != nil {
+		slog.Error(err.Error())
+		os.Exit(exitConfig)
 	}
+}
 
-	// validate only is a correct validator if specified
-	if len(only) > 0 {
-		var exists bool
-		for _, v := range cfg.Validators {
-			if strings.EqualFold(only, v.Name) {
-				exists = true
-				break
-			}
-		}
-		if !exists {
-			log.Fatalf("not an existing validator: %v", only)
-		}
+// errFailFast cancels the run on the first failed check with
+// --fail-fast
+var errFailFast = errors.New("a previous check failed")
+
+// runContext returns the context of a run of the checks, cancelled
+// after --max-duration if set
+func runContext() (context.Context, context.CancelFunc) {
+	if maxDuration > 0 {
+		return context.WithTimeout(context.Background(), maxDuration)
 	}
+	return context.WithCancel(context.Background())
+}
 
+// progress returns the callback reporting the checks as they
+// complete, using a progress bar or streaming ndjson events
+func progress(total int) func(name string, r aPIResult) {
 	var bar *progressbar.ProgressBar
-	if !isJsonOutput {
-		if len(only) > 0 {
-			bar = progressbar.Default(4)
-		} else {
-			bar = progressbar.Default(int64(len(cfg.Validators) * 4))
-		}
+	// the bar is written to stderr, never mixing with the results, and
+	// only if it is a terminal so it does not end up in the logs
+	if (output == "human" || output == "emoji") && !quiet && !noProgress && term.IsTerminal(int(os.Stderr.Fd())) {
+		bar = progressbar.NewOptions(total,
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionSetWidth(10),
+			progressbar.OptionThrottle(65*time.Millisecond),
+			progressbar.OptionShowCount(),
+			progressbar.OptionFullWidth(),
+			progressbar.OptionSetRenderBlankState(true),
+			progressbar.OptionClearOnFinish(),
+		)
 	}
 
-	res := []results{}
-
-	for _, v := range cfg.Validators {
-		if len(only) > 0 && !strings.EqualFold(only, v.Name) {
-			continue
-		}
-
-		newRes := results{
-			Name: v.Name,
+	return func(name string, r aPIResult) {
+		if output == "ndjson" && !quiet && !isNotConfigured(r) {
+			printJSON(checkEvent{Name: name, apiReport: newAPIReport(r)})
 		}
-
-		errStr := ""
-		timeTaken, err := checkGRPC(v.GRPC)
-		if err != nil {
-			errStr = err.Error()
-		}
-		newRes.APIResults = append(newRes.APIResults, aPIResult{
-			API:       "core",
-			TimeTaken: timeTaken,
-			Error:     errStr,
-		})
-		if !isJsonOutput {
+		if bar != nil {
+			bar.Describe(fmt.Sprintf("%-20.20s", name+" "+r.API))
 			bar.Add(1)
 		}
+	}
+}
 
-		errStr = ""
-		timeTaken, err = checkGRPCDN(v.GRPC)
-		if err != nil {
-			errStr = err.Error()
-		}
-		newRes.APIResults = append(newRes.APIResults, aPIResult{
-			API:       "datanode",
-			TimeTaken: timeTaken,
-			Error:     errStr,
-		})
-		if !isJsonOutput {
-			bar.Add(1)
+// selectValidators returns the validators named by --only, all of
+// them if not set, minus those named by --exclude, in configuration
+// order, exiting if a name does not match any of them
+func selectValidators(all []validator) []validator {
+	for _, name := range append(append([]string{}, only...), exclude...) {
+		if !slices.ContainsFunc(all, func(v validator) bool { return namedBy(v, name) }) {
+			fatalConfig("not an existing validator: %v", name)
 		}
+	}
 
-		errStr = ""
-		timeTaken, err = checkREST(v.REST)
-		if err != nil {
-			errStr = err.Error()
+	var selected []validator
+	for _, v := range all {
+		named := func(name string) bool { return namedBy(v, name) }
+		if len(only) > 0 && !slices.ContainsFunc(only, named) {
+			continue
 		}
-		newRes.APIResults = append(newRes.APIResults, aPIResult{
-			API:       "rest",
-			TimeTaken: timeTaken,
-			Error:     errStr,
-		})
-
-		if !isJsonOutput {
-			bar.Add(1)
+		if slices.ContainsFunc(exclude, named) {
+			continue
 		}
+		selected = append(selected, v)
+	}
+	return selected
+}
 
-		errStr = ""
-		timeTaken, err = checkGQL(v.GQL)
-		if err != nil {
-			errStr = err.Error()
-		}
-		newRes.APIResults = append(newRes.APIResults, aPIResult{
-			API:       "gql",
-			TimeTaken: timeTaken,
-			Error:     errStr,
-		})
-		if !isJsonOutput {
-			bar.Add(1)
-		}
+// namedBy returns whether the name given on the command line is the
+// one of the validator
+func namedBy(v validator, name string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), v.Name)
+}
 
-		res = append(res, newRes)
+// runChecks runs the checks on all the validators using a pool of
+// --concurrency workers, onResult is called after each check completes
+// if not nil
+func runChecks(ctx context.Context, validators []validator, checks []checker, onResult func(name string, r aPIResult)) []results {
+	// with --precheck the hosts and ports are checked first, the
+	// failures of the api checks being then attributed to them
+	var pre int
+	if precheck {
+		reachability := reachabilityChecks(checks)
+		pre = len(reachability)
+		checks = append(reachability, checks...)
 	}
 
-	if output == "human" {
-		printResults(res)
-	} else {
-		buf, err := json.Marshal(res)
-		if err != nil {
-			log.Fatalf("could not format output: %v", err)
+	res := make([]results, len(validators))
+	for i, v := range validators {
+		res[i] = results{
+			Name:       v.Name,
+			APIResults: make([]aPIResult, len(checks)),
 		}
-		fmt.Printf("%v\n", string(buf))
 	}
-}
 
-func printResults(results []results) {
-	t := table.NewWriter()
-	t.AppendHeader(table.Row{"validator", "core", "datanode", "rest", "graphql"})
+	type job struct {
+		validator, check int
+	}
 
-	t2 := table.NewWriter()
-	t2.AppendHeader(table.Row{"validator", "api", "error"})
+	workers := concurrency
+	if workers < 1 {
+		workers = 1
+	}
 
-	for _, v := range results {
-		resMap := map[string]aPIResult{}
-		for _, vr := range v.APIResults {
-			resMap[vr.API] = vr
-			if len(vr.Error) > 0 {
-				t2.AppendRow(table.Row{v.Name, vr.API, vr.Error})
+	// core and data-node checks share the grpc connection to a node
+	conns := newGRPCConns()
+	conns.core = slices.ContainsFunc(checks, func(c checker) bool { return c.name() == "core" })
+	defer conns.close()
+	ctx = withGRPCConns(ctx, conns)
+
+	var (
+		jobs  = make(chan job)
+		wg    sync.WaitGroup
+		preWG sync.WaitGroup
+		mu    sync.Mutex
+		trace = newRunTrace(time.Now())
+	)
+	preWG.Add(len(validators) * pre)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				v := validators[j.validator]
+				start := time.Now()
+				apiRes := sampleCheck(ctx, checks[j.check], v)
+				end := time.Now()
+				// make it explicit when the run was cancelled rather
+				// than reporting a random network error
+				if err := ctx.Err(); err != nil && len(apiRes.Error) > 0 {
+					switch cause := context.Cause(ctx); {
+					case errors.Is(err, context.DeadlineExceeded):
+						apiRes.Error = fmt.Sprintf("timed out, run deadline exceeded: %v", apiRes.Error)
+					case errors.Is(cause, errFailFast):
+						apiRes.Error = fmt.Sprintf("cancelled, %v: %v", cause, apiRes.Error)
+					default:
+						apiRes.Error = fmt.Sprintf("interrupted: %v", apiRes.Error)
+					}
+				}
+				if j.check >= pre && len(apiRes.Error) > 0 {
+					apiRes.Error = attributeFailure(res[j.validator].APIResults[:pre], checks[j.check].endpoint(v), apiRes.Error)
+				}
+				// each job owns its own slot, results are kept in
+				// configuration order
+				res[j.validator].APIResults[j.check] = apiRes
+				trace.addCheck(v.Name, apiRes, start, end)
+				if j.check < pre {
+					preWG.Done()
+				}
+
+				if onResult != nil {
+					mu.Lock()
+					onResult(v.Name, apiRes)
+					mu.Unlock()
+				}
 			}
-		}
-
-		t.AppendRow(table.Row{
-			v.Name,
-			coloredDuration(resMap["core"]),
-			coloredDuration(resMap["datanode"]),
-			coloredDuration(resMap["rest"]),
-			coloredDuration(resMap["gql"]),
-		})
+		}()
 	}
 
-	fmt.Println(t.Render())
-	fmt.Println(t2.Render())
-}
-
-func coloredDuration(res aPIResult) string {
-	green := color.New(color.FgGreen).SprintFunc()
-	red := color.New(color.FgRed).SprintFunc()
-
-	if len(res.Error) > 0 {
-		return red(res.TimeTaken.String())
+	// with --shuffle the checks are run in a random order, results are
+	// still reported in configuration order
+	dispatch := func(from, to int) {
+		var batch []job
+		for i := range validators {
+			for j := from; j < to; j++ {
+				batch = append(batch, job{validator: i, check: j})
+			}
+		}
+		if shuffle {
+			rand.Shuffle(len(batch), func(a, b int) { batch[a], batch[b] = batch[b], batch[a] })
+		}
+		for _, j := range batch {
+			jobs <- j
+		}
 	}
+	dispatch(0, pre)
+	preWG.Wait()
+	dispatch(pre, len(checks))
+	close(jobs)
+	wg.Wait()
+	// the optional checks of the validators without their endpoint are
+	// neither healthy nor failed
+	for i := range res {
+		res[i].APIResults = slices.DeleteFunc(res[i].APIResults, isNotConfigured)
+	}
+	checkNetworkVersion(res)
 
-	return green(res.TimeTaken.String())
+	if err := trace.export(time.Now()); err != nil {
+		slog.Warn("could not export the trace", "error", err)
+	}
+	return res
 }
 
-func checkREST(address string) (time.Duration, error) {
-	s, err := url.JoinPath(address, "api/v2/info")
-	if err != nil {
-		return 0, err
+// sampleCheck runs the check --samples times, the result holds the
+// latency statistics of the successful samples and the last error if
+// any sample failed
+func sampleCheck(ctx context.Context, c checker, v validator) aPIResult {
+	if samples <= 1 {
+		return runCheck(ctx, c, v)
 	}
 
-	now := time.Now()
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s, nil)
-	if err == nil {
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return time.Since(now), err
-		}
-		if resp.StatusCode != http.StatusOK {
-			return time.Since(now), fmt.Errorf("unexpected http status code: %v", resp.StatusCode)
+	var (
+		res       = aPIResult{API: c.name()}
+		stats     = &sampleStats{Count: samples}
+		all       []time.Duration
+		succeeded []time.Duration
+	)
+	for i := 0; i < samples; i++ {
+		r := runCheck(ctx, c, v)
+		res.Attempts += r.Attempts
+		all = append(all, r.TimeTaken)
+		if len(r.Error) > 0 {
+			stats.Failed++
+			res.Error = r.Error
+			continue
 		}
+		succeeded = append(succeeded, r.TimeTaken)
+		res.Details = r.Details
+		res.Timings = r.Timings
 	}
-	return time.Since(now), err
-}
 
-func checkGQL(address string) (time.Duration, error) {
-	s := address
-
-	now := time.Now()
+	if len(res.Error) > 0 {
+		res.Error = fmt.Sprintf("%d/%d samples failed, last error: %v", stats.Failed, stats.Count, res.Error)
+	}
+	if len(succeeded) <= 0 {
+		res.TimeTaken = average(all)
+		return res
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	sorted := append([]time.Duration{}, succeeded...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.Avg = average(succeeded)
+	stats.P95 = percentile(succeeded, 95)
+	res.TimeTaken = stats.Avg
+	res.Samples = stats
+	return res
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s, bytes.NewBuffer([]byte(gqlPayload)))
-	if err == nil {
-		req.Header.Add("Content-Type", "application/json")
-		resp, err := http.DefaultClient.Do(req)
+// runCheck runs the check on the validator, retrying failures up to
+// --retries times with an exponential backoff
+func runCheck(ctx context.Context, c checker, v validator) aPIResult {
+	var res aPIResult
+	for attempt := 1; ; attempt++ {
+		slog.Debug("check started", "validator", v.Name, "api", c.name(), "attempt", attempt)
+		errStr := ""
+		t, details, err := c.run(withValidator(ctx, v), v)
 		if err != nil {
-			return time.Since(now), err
+			errStr = err.Error()
+		}
+		slog.Debug("check finished", "validator", v.Name, "api", c.name(), "duration", t.total(), "error", err)
+		res = aPIResult{
+			API:       c.name(),
+			TimeTaken: t.total(),
+			Timings:   t,
+			Error:     errStr,
+			Details:   details,
+			Attempts:  attempt,
+		}
+		if err == nil || attempt > retries {
+			return res
 		}
-		if resp.StatusCode != http.StatusOK {
-			return time.Since(now), fmt.Errorf("unexpected http status code: %v", resp.StatusCode)
+		delay := backoff(attempt)
+		slog.Debug("check failed, retrying", "validator", v.Name, "api", c.name(), "attempt", attempt, "backoff", delay)
+		select {
+		case <-ctx.Done():
+			return res
+		case <-time.After(delay):
 		}
 	}
-
-	return time.Since(now), err
 }
 
-func checkGRPC(address string) (time.Duration, error) {
-	useTLS := strings.HasPrefix(address, "tls://")
+// maxRetryBackoff caps the delay between two attempts, the doubling
+// overflowing after a few dozen retries
+const maxRetryBackoff = time.Minute
 
-	var creds credentials.TransportCredentials
-	if useTLS {
-		address = address[6:]
-		creds = credentials.NewClientTLSFromCert(nil, "")
-	} else {
-		creds = insecure.NewCredentials()
+// backoff returns the delay before the next attempt, doubling on each
+// attempt up to maxRetryBackoff with up to 50% of random jitter
+func backoff(attempt int) time.Duration {
+	d := retryBackoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
 	}
-
-	connection, err := grpc.Dial(address, grpc.WithTransportCredentials(creds))
-	if err != nil {
-		return 0, err
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
 	}
-
-	now := time.Now()
-
-	connCore := apipb.NewCoreServiceClient(connection)
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	_, err = connCore.Statistics(ctx, &apipb.StatisticsRequest{})
-
-	return time.Since(now), err
-}
-
-func checkGRPCDN(address string) (time.Duration, error) {
-	useTLS := strings.HasPrefix(address, "tls://")
-
-	var creds credentials.TransportCredentials
-	if useTLS {
-		address = address[6:]
-		creds = credentials.NewClientTLSFromCert(nil, "")
-	} else {
-		creds = insecure.NewCredentials()
-	}
-
-	connection, err := grpc.Dial(address, grpc.WithTransportCredentials(creds))
-	if err != nil {
-		return 0, err
+	if d <= 0 {
+		return 0
 	}
-
-	now := time.Now()
-
-	connDT := dnapipb.NewTradingDataServiceClient(connection)
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	_, err = connDT.Info(ctx, &dnapipb.InfoRequest{})
-
-	return time.Since(now), err
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
 }
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackoffLargeAttempt checks the delay stays within maxRetryBackoff
+// once the doubling of --retry-backoff would overflow
+func TestBackoffLargeAttempt(t *testing.T) {
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = 200 * time.Millisecond
+	for _, attempt := range []int{1, 10, 64, 100, 1000} {
+		if d := backoff(attempt); d < 0 || d > maxRetryBackoff {
+			t.Errorf("backoff of attempt %d is %v", attempt, d)
+		}
+	}
+}
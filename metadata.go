@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var metadataTimeout time.Duration
+
+// metadataLink is an url announced by a node of the network and the
+// outcome of fetching it
+type metadataLink struct {
+	node, kind, url string
+	err             error
+}
+
+func newMetadataCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metadata",
+		Short: "Verify the website and avatar urls announced by the nodes of the network resolve",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			_, cfg := loadConfig()
+			checkMetadata(selectValidators(cfg.Validators))
+		},
+	}
+	cmd.Flags().StringSliceVar(&only, "only", nil, "list the nodes from the data-nodes of these validators only")
+	cmd.Flags().DurationVar(&metadataTimeout, "timeout", 10*time.Second, "timeout of the fetch of each url")
+	_ = cmd.RegisterFlagCompletionFunc("only", completeValidators)
+	return cmd
+}
+
+// checkMetadata lists the nodes of the network from the first data-node
+// answering, then fetches the urls they announced
+func checkMetadata(validators []validator) {
+	var nodes networkNodes
+	var err error
+	for _, v := range validators {
+		if len(v.REST) <= 0 {
+			continue
+		}
+		_, _, err = getJSONTimeout(context.Background(), metadataTimeout, v.REST, "api/v2/nodes", nil, &nodes)
+		if err == nil {
+			break
+		}
+		slog.Warn("could not list the nodes", "validator", v.Name, "error", err)
+	}
+	if err != nil || len(nodes.Nodes.Edges) <= 0 {
+		fatalConfig("could not list the nodes of the network from any data-node")
+	}
+
+	var links []*metadataLink
+	for _, e := range nodes.Nodes.Edges {
+		n := e.Node
+		name := orDash(n.Name)
+		links = append(links,
+			&metadataLink{node: name, kind: "website", url: n.InfoURL},
+			&metadataLink{node: name, kind: "avatar", url: n.AvatarURL},
+		)
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].node < links[j].node })
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for _, l := range links {
+		if len(l.url) <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(l *metadataLink) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			l.err = fetchLink(l.url)
+		}(l)
+	}
+	wg.Wait()
+
+	fmt.Println(renderMetadata(links))
+	for _, l := range links {
+		if l.err != nil {
+			os.Exit(exitFailed)
+		}
+	}
+}
+
+// fetchLink returns an error if the url does not resolve to a
+// successful response
+func fetchLink(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("not an http url")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("http status code: %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// renderMetadata renders a row per announced url, the broken ones in
+// red and the missing ones in yellow
+func renderMetadata(links []*metadataLink) string {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"node", "link", "url", "status"})
+	var broken int
+	for _, l := range links {
+		status := "ok"
+		switch {
+		case len(l.url) <= 0:
+			status = yellow("not announced")
+		case l.err != nil:
+			status = red(l.err.Error())
+			broken++
+		}
+		t.AppendRow(table.Row{l.node, l.kind, orDash(l.url), status})
+	}
+	t.SetTitle(fmt.Sprintf("%d nodes, %d broken urls", len(links)/2, broken))
+	return t.Render()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// labelEscaper escapes label values following the prometheus text
+// exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// registerMetrics exposes the latest run on /metrics using the
+// prometheus text format
+func registerMetrics(mux *http.ServeMux, network string, history *runHistory, regions *regionReports) {
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		if last, ok := history.latest(); ok {
+			writeMetrics(w, network, last)
+		}
+		if all := regions.all(); len(all) > 0 {
+			writeRegionMetrics(w, network, all)
+		}
+	})
+}
+
+func writeMetrics(w io.Writer, network string, r run) {
+	labels := func(name, api string) string {
+		return fmt.Sprintf(`{network="%v",validator="%v",api="%v"}`,
+			labelEscaper.Replace(network), labelEscaper.Replace(name), labelEscaper.Replace(api))
+	}
+
+	fmt.Fprintln(w, "# HELP vega_validator_api_up Whether the last check of the validator API succeeded.")
+	fmt.Fprintln(w, "# TYPE vega_validator_api_up gauge")
+	for _, v := range r.Results {
+		for _, vr := range v.APIResults {
+			up := 1
+			if len(vr.Error) > 0 {
+				up = 0
+			}
+			fmt.Fprintf(w, "vega_validator_api_up%v %d\n", labels(v.Name, vr.API), up)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP vega_validator_api_latency_seconds Time taken by the last check of the validator API.")
+	fmt.Fprintln(w, "# TYPE vega_validator_api_latency_seconds gauge")
+	for _, v := range r.Results {
+		for _, vr := range v.APIResults {
+			fmt.Fprintf(w, "vega_validator_api_latency_seconds%v %v\n", labels(v.Name, vr.API), vr.TimeTaken.Seconds())
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP vega_validator_block_height Block height reported by the validator API.")
+	fmt.Fprintln(w, "# TYPE vega_validator_block_height gauge")
+	for _, v := range r.Results {
+		for _, vr := range v.APIResults {
+			height, err := strconv.ParseUint(vr.Details[detailBlockHeight], 10, 64)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "vega_validator_block_height%v %d\n", labels(v.Name, vr.API), height)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP vega_checks_last_run_timestamp_seconds Time the last run of the checks started.")
+	fmt.Fprintln(w, "# TYPE vega_checks_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(w, "vega_checks_last_run_timestamp_seconds{network=\"%v\"} %d\n", labelEscaper.Replace(network), r.Timestamp.Unix())
+}
+
+// writeRegionMetrics exposes the latest reports of the agents, labelled
+// with their region
+func writeRegionMetrics(w io.Writer, network string, regions []regionReport) {
+	labels := func(region, name, api string) string {
+		return fmt.Sprintf(`{network="%v",region="%v",validator="%v",api="%v"}`,
+			labelEscaper.Replace(network), labelEscaper.Replace(region), labelEscaper.Replace(name), labelEscaper.Replace(api))
+	}
+
+	fmt.Fprintln(w, "# HELP vega_validator_api_region_up Whether the last check of the validator API from the region succeeded.")
+	fmt.Fprintln(w, "# TYPE vega_validator_api_region_up gauge")
+	for _, rr := range regions {
+		for _, v := range rr.Report.Validators {
+			for _, a := range v.APIResults {
+				up := 1
+				if a.Status == apiStatusError {
+					up = 0
+				}
+				fmt.Fprintf(w, "vega_validator_api_region_up%v %d\n", labels(rr.Region, v.Name, a.API), up)
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP vega_validator_api_region_latency_seconds Time taken by the last check of the validator API from the region.")
+	fmt.Fprintln(w, "# TYPE vega_validator_api_region_latency_seconds gauge")
+	for _, rr := range regions {
+		for _, v := range rr.Report.Validators {
+			for _, a := range v.APIResults {
+				fmt.Fprintf(w, "vega_validator_api_region_latency_seconds%v %v\n", labels(rr.Region, v.Name, a.API), a.TimeTakenMS/1000)
+			}
+		}
+	}
+}
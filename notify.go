@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const notifyTimeout = 10 * time.Second
+
+// notification is the outcome of a run as sent to the notifiers
+type notification struct {
+	Network   string
+	Timestamp time.Time
+	Results   []results
+	// Changes lists the APIs which failed, recovered or regressed
+	// since the previous run, or compared with their baseline
+	Changes []diffEntry
+	// FirstRun is set when there is no previous run to compare with,
+	// every failure being then new
+	FirstRun bool
+}
+
+// hasFailures returns true if any check of the run failed
+func (n notification) hasFailures() bool {
+	for _, v := range n.Results {
+		if v.failures() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// alerts returns the problems of the network as a whole
+func (n notification) alerts() []string {
+	return networkAlerts(n.Results)
+}
+
+// notifier delivers the results of a run to an external service
+type notifier interface {
+	name() string
+	notify(ctx context.Context, n notification) error
+}
+
+type notifiersConfig struct {
+	Slack    *slackConfig    `json:"slack,omitempty"`
+	Telegram *telegramConfig `json:"telegram,omitempty"`
+	Opsgenie *opsgenieConfig `json:"opsgenie,omitempty"`
+	Webhooks []webhookConfig `json:"webhooks,omitempty"`
+	Email    *emailConfig    `json:"email,omitempty"`
+}
+
+// build returns the notifiers enabled in the configuration
+func (c notifiersConfig) build() ([]notifier, error) {
+	notifiers := []notifier{}
+	if c.Slack != nil && len(c.Slack.WebhookURL) > 0 {
+		notifiers = append(notifiers, &slackNotifier{cfg: *c.Slack})
+	}
+	if c.Telegram != nil && len(c.Telegram.BotToken) > 0 {
+		notifiers = append(notifiers, &telegramNotifier{cfg: *c.Telegram})
+	}
+	if c.Opsgenie != nil && len(c.Opsgenie.APIKey) > 0 {
+		notifiers = append(notifiers, newOpsgenieNotifier(*c.Opsgenie))
+	}
+	if c.Email != nil && len(c.Email.Host) > 0 {
+		notifiers = append(notifiers, &emailNotifier{cfg: *c.Email})
+	}
+	for i, w := range c.Webhooks {
+		wn, err := newWebhookNotifier(w)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook #%d: %w", i+1, err)
+		}
+		notifiers = append(notifiers, wn)
+	}
+	return notifiers, nil
+}
+
+// notifyAll sends the notification to all the notifiers, failures
+// are logged but do not stop the others
+func notifyAll(notifiers []notifier, n notification) {
+	for _, nt := range notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		if err := nt.notify(ctx, n); err != nil {
+			slog.Error("could not notify", "notifier", nt.name(), "error", err)
+		} else {
+			slog.Debug("notified", "notifier", nt.name())
+		}
+		cancel()
+	}
+}
+
+// postJSON sends the payload to the url and expects a 2xx status code
+func postJSON(ctx context.Context, url string, headers map[string]string, payload any) error {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return post(ctx, url, headers, buf)
+}
+
+// post sends the body to the url and expects a 2xx status code, the
+// content type defaults to json unless set in the headers
+func post(ctx context.Context, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return stripURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return stripURL(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected http status code: %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// stripURL returns the cause of an error of net/url or net/http without
+// the url, which holds the token of most notifiers and is logged
+func stripURL(err error) error {
+	if cause := errors.Unwrap(err); cause != nil {
+		return cause
+	}
+	return err
+}
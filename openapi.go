@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+func init() {
+	register(apiCheck{"openapi", func(v validator) string { return v.REST }, checkOpenAPI}, profileDeep)
+}
+
+const (
+	detailOpenAPIVersion = "openapi_version"
+	detailOpenAPIPaths   = "openapi_paths"
+)
+
+var openAPIPath string
+
+// openAPISpec is the part of an OpenAPI (or Swagger 2.0) document
+// telling it is one
+type openAPISpec struct {
+	OpenAPI string                     `json:"openapi"`
+	Swagger string                     `json:"swagger"`
+	Paths   map[string]json.RawMessage `json:"paths"`
+}
+
+// checkOpenAPI checks the data-node serves its OpenAPI document and
+// that it describes at least one path, gateways routing the api but
+// not the documentation breaking the generated clients
+func checkOpenAPI(ctx context.Context, address string) (timings, map[string]string, error) {
+	var spec openAPISpec
+	t, details, err := getJSON(ctx, address, openAPIPath, nil, &spec)
+	if err != nil {
+		return t, details, err
+	}
+	switch {
+	case len(spec.OpenAPI) > 0:
+		details[detailOpenAPIVersion] = spec.OpenAPI
+	case len(spec.Swagger) > 0:
+		details[detailOpenAPIVersion] = spec.Swagger
+	default:
+		return t, details, errors.New("not an OpenAPI document")
+	}
+	details[detailOpenAPIPaths] = strconv.Itoa(len(spec.Paths))
+	if len(spec.Paths) <= 0 {
+		return t, details, errors.New("OpenAPI document without paths")
+	}
+	return t, details, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+const opsgenieDefaultURL = "https://api.opsgenie.com"
+
+// opsgeniePriority maps the status of an API to an alert priority,
+// an empty API or status matches any
+type opsgeniePriority struct {
+	API      string `json:"api"`
+	Status   string `json:"status"`
+	Priority string `json:"priority"`
+}
+
+type opsgenieConfig struct {
+	APIKey string `json:"api_key"`
+	// APIURL allows using the EU instance (https://api.eu.opsgenie.com)
+	APIURL     string             `json:"api_url"`
+	Priorities []opsgeniePriority `json:"priorities"`
+	// DefaultPriority is used for failed checks matching no priority,
+	// slow checks are only alerted on if matching a priority
+	DefaultPriority string `json:"default_priority"`
+}
+
+// opsgenieNotifier opens an alert per failing validator API, and
+// closes it once the API is healthy again
+type opsgenieNotifier struct {
+	cfg opsgenieConfig
+
+	mu sync.Mutex
+	// open tracks the aliases of the alerts opened by this process
+	open map[string]bool
+	// closedAll is set once the first notification of the process
+	// closed the alerts of every healthy API, the alerts opened by a
+	// previous run or process being unknown
+	closedAll bool
+}
+
+func newOpsgenieNotifier(cfg opsgenieConfig) *opsgenieNotifier {
+	if len(cfg.APIURL) <= 0 {
+		cfg.APIURL = opsgenieDefaultURL
+	}
+	if len(cfg.DefaultPriority) <= 0 {
+		cfg.DefaultPriority = "P3"
+	}
+	return &opsgenieNotifier{cfg: cfg, open: map[string]bool{}}
+}
+
+func (o *opsgenieNotifier) name() string { return "opsgenie" }
+
+// priority returns the priority of the alert for the API result, or
+// false if it should not be alerted on
+func (o *opsgenieNotifier) priority(res aPIResult) (string, bool) {
+	status := apiStatus(res)
+	if status == apiStatusOK {
+		return "", false
+	}
+	for _, p := range o.cfg.Priorities {
+		if (len(p.API) <= 0 || p.API == res.API) && (len(p.Status) <= 0 || p.Status == status) {
+			return p.Priority, true
+		}
+	}
+	if status == apiStatusError {
+		return o.cfg.DefaultPriority, true
+	}
+	return "", false
+}
+
+func (o *opsgenieNotifier) notify(ctx context.Context, n notification) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	headers := map[string]string{"Authorization": "GenieKey " + o.cfg.APIKey}
+
+	recovered := map[string]bool{}
+	for _, c := range n.Changes {
+		if c.Change == changeRecovered {
+			recovered[c.Name+"/"+c.API] = true
+		}
+	}
+
+	var (
+		lastErr  error
+		closeAll = !o.closedAll
+	)
+	for _, v := range n.Results {
+		for _, vr := range v.APIResults {
+			alias := fmt.Sprintf("vega-%v-%v-%v", n.Network, v.Name, vr.API)
+
+			priority, ok := o.priority(vr)
+			if !ok {
+				// closing an alert by alias is idempotent
+				if closeAll || o.open[alias] || recovered[v.Name+"/"+vr.API] {
+					closeURL := fmt.Sprintf("%v/v2/alerts/%v/close?identifierType=alias", o.cfg.APIURL, url.PathEscape(alias))
+					if err := postJSON(ctx, closeURL, headers, map[string]string{"source": "check_validator_setup"}); err != nil {
+						lastErr = err
+						continue
+					}
+					delete(o.open, alias)
+				}
+				continue
+			}
+
+			description := fmt.Sprintf("%v took %v", vr.API, vr.TimeTaken)
+			if len(vr.Error) > 0 {
+				description = vr.Error
+			}
+			// opsgenie deduplicates alerts with the same alias
+			err := postJSON(ctx, o.cfg.APIURL+"/v2/alerts", headers, map[string]any{
+				"message":     fmt.Sprintf("%v validator %v: %v is %v", n.Network, v.Name, vr.API, apiStatus(vr)),
+				"alias":       alias,
+				"description": description,
+				"priority":    priority,
+				"source":      "check_validator_setup",
+				"tags":        []string{"vega", n.Network, v.Name, vr.API},
+			})
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			o.open[alias] = true
+		}
+	}
+
+	o.closedAll = o.closedAll || lastErr == nil
+	return lastErr
+}
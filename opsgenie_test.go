@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestOpsgenieCloseUnknownAlerts checks the alerts of the healthy APIs
+// are closed by a new process, which does not know the alerts opened
+// before it, and then only once they recover
+func TestOpsgenieCloseUnknownAlerts(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/close") {
+			mu.Lock()
+			closed = append(closed, r.URL.Path)
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	o := newOpsgenieNotifier(opsgenieConfig{APIKey: "key", APIURL: srv.URL})
+	n := notification{
+		Network: "testnet",
+		Results: []results{{Name: "v", APIResults: []aPIResult{{API: "rest"}}}},
+	}
+	for i := 0; i < 2; i++ {
+		if err := o.notify(context.Background(), n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(closed) != 1 || closed[0] != "/v2/alerts/vega-testnet-v-rest/close" {
+		t.Fatalf("closed %v, expected the alert of v rest once", closed)
+	}
+
+	n.Changes = []diffEntry{{Name: "v", API: "rest", Change: changeRecovered}}
+	if err := o.notify(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 2 {
+		t.Errorf("closed %v, expected the recovered alert to be closed", closed)
+	}
+}
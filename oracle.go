@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func init() {
+	register(apiCheck{"oracle", func(v validator) string { return v.REST }, checkOracle}, profileStandard)
+}
+
+const (
+	detailOracleSpecs = "oracle_specs"
+	detailOracleAge   = "oracle_age"
+)
+
+var maxOracleAge time.Duration
+
+// oracleSpecs is the part of the response of the data-node
+// /api/v2/oracle/specs endpoint
+type oracleSpecs struct {
+	OracleSpecs struct {
+		Edges []struct{} `json:"edges"`
+	} `json:"oracleSpecs"`
+}
+
+// oracleData is the part of the response of the data-node
+// /api/v2/oracle/data endpoint
+type oracleData struct {
+	OracleData struct {
+		Edges []struct {
+			Node struct {
+				ExternalData struct {
+					Data struct {
+						BroadcastAt string `json:"broadcastAt"`
+					} `json:"data"`
+				} `json:"externalData"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"oracleData"`
+}
+
+// checkOracle checks the data-node serves the oracle specs and that
+// the newest oracle data is not older than --max-oracle-age, stale
+// oracles preventing the markets from settling
+func checkOracle(ctx context.Context, address string) (timings, map[string]string, error) {
+	var specs oracleSpecs
+	t, details, err := getJSON(ctx, address, "api/v2/oracle/specs", nil, &specs)
+	if err != nil {
+		return t, details, err
+	}
+	details[detailOracleSpecs] = strconv.Itoa(len(specs.OracleSpecs.Edges))
+	if len(specs.OracleSpecs.Edges) <= 0 {
+		// no market relies on an oracle
+		return t, details, nil
+	}
+
+	var data oracleData
+	dt, _, err := getJSON(ctx, address, "api/v2/oracle/data", nil, &data)
+	t.Request += dt.total()
+	if err != nil {
+		return t, details, fmt.Errorf("could not list the oracle data: %w", err)
+	}
+
+	var newest int64
+	for _, e := range data.OracleData.Edges {
+		ns, err := strconv.ParseInt(e.Node.ExternalData.Data.BroadcastAt, 10, 64)
+		if err == nil && ns > newest {
+			newest = ns
+		}
+	}
+	if newest <= 0 {
+		return t, details, errors.New("no oracle data")
+	}
+
+	age := time.Since(time.Unix(0, newest)).Truncate(time.Second)
+	details[detailOracleAge] = age.String()
+	if age > maxOracleAge {
+		return t, details, fmt.Errorf("stale oracle data: newest is %v old", age)
+	}
+	return t, details, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const otlpTimeout = 5 * time.Second
+
+var (
+	otlpEndpoint string
+	otlpHeaders  map[string]string
+)
+
+// otlpURL returns the url of an OTLP/HTTP signal, --otlp-endpoint
+// defaulting to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable,
+// empty if neither is set
+func otlpURL(signal string) string {
+	endpoint := otlpEndpoint
+	if len(endpoint) <= 0 {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if len(endpoint) <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/v1/" + signal
+}
+
+// otlpResource identifies the tool in the exported telemetry
+func otlpResource() map[string]any {
+	return map[string]any{
+		"attributes": []otlpAttribute{
+			otlpString("service.name", "check_validator_setup"),
+			otlpString("service.version", version),
+		},
+	}
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpString(k, v string) otlpAttribute {
+	return otlpAttribute{Key: k, Value: map[string]any{"stringValue": v}}
+}
+
+func otlpInt(k string, v int64) otlpAttribute {
+	// 64 bits integers are encoded as strings in the json encoding
+	return otlpAttribute{Key: k, Value: map[string]any{"intValue": strconv.FormatInt(v, 10)}}
+}
+
+// otlpExport posts an OTLP/HTTP json payload of a signal [traces|metrics]
+func otlpExport(signal string, payload any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+	return postJSON(ctx, otlpURL(signal), otlpHeaders, payload)
+}
+
+type otlpMetric struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Unit        string    `json:"unit"`
+	Gauge       otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpAttribute `json:"attributes"`
+	TimeUnixNano string          `json:"timeUnixNano"`
+	AsInt        *string         `json:"asInt,omitempty"`
+	AsDouble     *float64        `json:"asDouble,omitempty"`
+}
+
+// pushMetrics exports the gauges of a run exposed on /metrics to the
+// OTLP endpoint, if any
+func pushMetrics(network string, r run) {
+	if len(otlpURL("metrics")) <= 0 {
+		return
+	}
+
+	now := unixNano(time.Now())
+	point := func(attrs []otlpAttribute, i int64, d *float64) otlpDataPoint {
+		p := otlpDataPoint{Attributes: attrs, TimeUnixNano: now, AsDouble: d}
+		if d == nil {
+			s := strconv.FormatInt(i, 10)
+			p.AsInt = &s
+		}
+		return p
+	}
+
+	var up, latency, height []otlpDataPoint
+	for _, v := range r.Results {
+		for _, vr := range v.APIResults {
+			attrs := []otlpAttribute{
+				otlpString("network", network),
+				otlpString("validator", v.Name),
+				otlpString("api", vr.API),
+			}
+			ok := int64(1)
+			if len(vr.Error) > 0 {
+				ok = 0
+			}
+			up = append(up, point(attrs, ok, nil))
+			seconds := vr.TimeTaken.Seconds()
+			latency = append(latency, point(attrs, 0, &seconds))
+			if h, err := strconv.ParseInt(vr.Details[detailBlockHeight], 10, 64); err == nil {
+				height = append(height, point(attrs, h, nil))
+			}
+		}
+	}
+
+	metrics := []otlpMetric{
+		{"vega_validator_api_up", "Whether the last check of the validator API succeeded.", "1", otlpGauge{up}},
+		{"vega_validator_api_latency_seconds", "Time taken by the last check of the validator API.", "s", otlpGauge{latency}},
+		{"vega_validator_block_height", "Block height reported by the validator API.", "1", otlpGauge{height}},
+		{"vega_checks_last_run_timestamp_seconds", "Time the last run of the checks started.", "s", otlpGauge{[]otlpDataPoint{
+			point([]otlpAttribute{otlpString("network", network)}, r.Timestamp.Unix(), nil),
+		}}},
+	}
+
+	// gauges without data points, e.g. no block height, are left out
+	exported := metrics[:0]
+	for _, m := range metrics {
+		if len(m.Gauge.DataPoints) > 0 {
+			exported = append(exported, m)
+		}
+	}
+
+	payload := map[string]any{
+		"resourceMetrics": []map[string]any{{
+			"resource": otlpResource(),
+			"scopeMetrics": []map[string]any{{
+				"scope":   map[string]string{"name": "check_validator_setup", "version": version},
+				"metrics": exported,
+			}},
+		}},
+	}
+	if err := otlpExport("metrics", payload); err != nil {
+		slog.Warn("could not export the metrics", "error", err)
+	}
+}
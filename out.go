@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	timestampPlaceholder = "{timestamp}"
+	timestampLayout      = "20060102T150405Z"
+)
+
+// outputPath resolves the file the results are written to: a
+// {timestamp} placeholder is replaced by the run time, and an existing
+// directory (or a path ending with a separator) gets a timestamped
+// file named after the network
+func outputPath(out, network string, timestamp time.Time) string {
+	if fi, err := os.Stat(out); (err == nil && fi.IsDir()) || strings.HasSuffix(out, string(os.PathSeparator)) {
+		out = filepath.Join(out, network+"-"+timestampPlaceholder+".json")
+	}
+	return strings.ReplaceAll(out, timestampPlaceholder, timestamp.UTC().Format(timestampLayout))
+}
+
+// writeReport saves the json report to disk, creating the parent
+// directories if required
+func writeReport(path string, r report) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	buf, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(buf, '\n'), 0o644)
+}
@@ -0,0 +1,623 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// jsonSchemaVersion is bumped on every breaking change of the json output
+const jsonSchemaVersion = 2
+
+// report is the document produced by the json output
+type report struct {
+	SchemaVersion int               `json:"schema_version"`
+	ToolVersion   string            `json:"tool_version"`
+	ToolCommit    string            `json:"tool_commit,omitempty"`
+	Network       string            `json:"network"`
+	Timestamp     time.Time         `json:"timestamp"`
+	Validators    []validatorReport `json:"validators"`
+	Diff          []diffEntry       `json:"diff,omitempty"`
+	// Alerts lists the problems of the network as a whole
+	Alerts []string `json:"alerts,omitempty"`
+	// Partial is set when the run was interrupted before all the
+	// checks completed
+	Partial bool `json:"partial,omitempty"`
+}
+
+type validatorReport struct {
+	Name       string      `json:"name"`
+	Status     string      `json:"status"`
+	APIResults []apiReport `json:"api_results"`
+}
+
+type apiReport struct {
+	API         string            `json:"api"`
+	Status      string            `json:"status"`
+	TimeTakenMS float64           `json:"time_taken_ms"`
+	Error       string            `json:"error,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Timings     timingsReport     `json:"timings"`
+	Attempts    int               `json:"attempts"`
+	Samples     *sampleReport     `json:"samples,omitempty"`
+}
+
+type timingsReport struct {
+	DNSMS     float64 `json:"dns_ms"`
+	ConnectMS float64 `json:"connect_ms"`
+	TLSMS     float64 `json:"tls_ms"`
+	RequestMS float64 `json:"request_ms"`
+}
+
+type sampleReport struct {
+	Count  int     `json:"count"`
+	Failed int     `json:"failed"`
+	MinMS  float64 `json:"min_ms"`
+	AvgMS  float64 `json:"avg_ms"`
+	MaxMS  float64 `json:"max_ms"`
+	P95MS  float64 `json:"p95_ms"`
+}
+
+// templateFuncs are available to the user templates, e.g:
+// {"text": {{ json .Network }}}
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	},
+}
+
+// checkEvent is a single API result as streamed by the ndjson output
+type checkEvent struct {
+	Name string `json:"name"`
+	apiReport
+}
+
+func newReport(network string, timestamp time.Time, res []results, changes []diffEntry) report {
+	r := report{
+		SchemaVersion: jsonSchemaVersion,
+		ToolVersion:   version,
+		ToolCommit:    newBuildInfo().Commit,
+		Network:       network,
+		Timestamp:     timestamp.UTC(),
+		Validators:    []validatorReport{},
+		Diff:          changes,
+		Alerts:        networkAlerts(res),
+	}
+	for _, v := range res {
+		vr := validatorReport{
+			Name:       v.Name,
+			Status:     v.status(),
+			APIResults: []apiReport{},
+		}
+		for _, a := range v.APIResults {
+			vr.APIResults = append(vr.APIResults, newAPIReport(a))
+		}
+		r.Validators = append(r.Validators, vr)
+	}
+	return r
+}
+
+func newAPIReport(res aPIResult) apiReport {
+	var samples *sampleReport
+	if s := res.Samples; s != nil {
+		samples = &sampleReport{
+			Count:  s.Count,
+			Failed: s.Failed,
+			MinMS:  toMS(s.Min),
+			AvgMS:  toMS(s.Avg),
+			MaxMS:  toMS(s.Max),
+			P95MS:  toMS(s.P95),
+		}
+	}
+
+	return apiReport{
+		API:         res.API,
+		Status:      apiStatus(res),
+		TimeTakenMS: toMS(res.TimeTaken),
+		Error:       res.Error,
+		Details:     res.Details,
+		Timings: timingsReport{
+			DNSMS:     toMS(res.Timings.DNS),
+			ConnectMS: toMS(res.Timings.Connect),
+			TLSMS:     toMS(res.Timings.TLS),
+			RequestMS: toMS(res.Timings.Request),
+		},
+		Attempts: res.Attempts,
+		Samples:  samples,
+	}
+}
+
+// toMS converts a duration to a number of milliseconds
+func toMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// fromMS converts a number of milliseconds to a duration
+func fromMS(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// sortResults orders the results in place, keeping the configuration
+// order between equal entries
+func sortResults(res []results, by string, desc bool) {
+	var less func(a, b results) bool
+	switch by {
+	case "name":
+		less = func(a, b results) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "latency":
+		less = func(a, b results) bool { return a.totalTime() < b.totalTime() }
+	case "failures":
+		less = func(a, b results) bool { return a.failures() < b.failures() }
+	default:
+		return
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if desc {
+			return less(res[j], res[i])
+		}
+		return less(res[i], res[j])
+	})
+}
+
+func printOutput(network string, startedAt time.Time, res []results, changes []diffEntry, partial bool) {
+	r := newReport(network, startedAt, res, changes)
+	r.Partial = partial
+
+	switch output {
+	case "human", "emoji":
+		cell := coloredDuration
+		if output == "emoji" {
+			cell = emojiDuration
+		}
+		if partial {
+			fmt.Println(color.New(color.FgRed, color.Bold).Sprint("PARTIAL RESULTS: the run was interrupted"))
+		}
+		for _, alert := range r.Alerts {
+			fmt.Println(color.New(color.FgRed, color.Bold).Sprint("NETWORK ALERT: " + alert))
+		}
+		printResults(res, cell)
+		if changes != nil {
+			fmt.Println(renderDiff(changes))
+		}
+	case "checkmk":
+		printCheckmk(res)
+	case "github":
+		printGitHub(r, res)
+	case "tap":
+		printTAP(res)
+		if partial {
+			fmt.Println("# partial results, the run was interrupted")
+		}
+	case "ndjson":
+		// results were already streamed as they completed
+	case "template":
+		if err := outputTemplate.Execute(os.Stdout, r); err != nil {
+			fatalConfig("could not execute template: %v", err)
+		}
+	default:
+		printJSON(r)
+	}
+}
+
+// apiHeaders maps the API names to the human table column headers
+var apiHeaders = map[string]string{
+	"gql":           "graphql",
+	"corerest":      "core rest",
+	"gqlcomplexity": "graphql complexity",
+}
+
+func apiHeader(api string) string {
+	if h, ok := apiHeaders[api]; ok {
+		return h
+	}
+	return api
+}
+
+func printResults(results []results, cell func(aPIResult) string) {
+	// only display the APIs which were actually checked
+	apis := resultAPIs(results)
+
+	cols := tableColumns(apis)
+	header := table.Row{"validator"}
+	for _, c := range cols {
+		header = append(header, c.header)
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(header)
+	if units != "auto" {
+		// values in a single unit line up when right aligned
+		var configs []table.ColumnConfig
+		for i, c := range cols {
+			if len(c.api) > 0 {
+				configs = append(configs, table.ColumnConfig{Number: i + 2, Align: text.AlignRight})
+			}
+		}
+		t.SetColumnConfigs(configs)
+	}
+
+	t2 := table.NewWriter()
+	t2.AppendHeader(table.Row{"validator", "api", "error"})
+
+	for _, v := range results {
+		resMap := map[string]aPIResult{}
+		for _, vr := range v.APIResults {
+			resMap[vr.API] = vr
+			if len(vr.Error) > 0 {
+				t2.AppendRow(table.Row{v.Name, vr.API, vr.Error})
+			}
+		}
+
+		row := table.Row{v.Name}
+		for _, c := range cols {
+			if len(c.api) <= 0 {
+				row = append(row, c.value(v, resMap))
+			} else if vr, ok := resMap[c.api]; ok {
+				row = append(row, cell(vr))
+			} else {
+				row = append(row, "-")
+			}
+		}
+		t.AppendRow(row)
+	}
+
+	fmt.Println(t.Render())
+	fmt.Println(t2.Render())
+	if redirects := renderRedirects(results); len(redirects) > 0 {
+		fmt.Println(redirects)
+	}
+	if compat := renderCompat(results); len(compat) > 0 {
+		fmt.Println(compat)
+	}
+	for _, api := range detailedAPIs {
+		if t := renderDetails(results, api); len(t) > 0 {
+			fmt.Println(t)
+		}
+	}
+	if showTimings {
+		fmt.Println(renderTimings(results))
+	}
+	if samples > 1 {
+		fmt.Println(renderSamples(results))
+	}
+	fmt.Println(renderSummary(results))
+}
+
+// renderRedirects lists the checks which followed redirects, empty if
+// none did
+func renderRedirects(results []results) string {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	t := table.NewWriter()
+	t.SetTitle("redirects")
+	t.AppendHeader(table.Row{"validator", "api", "redirects", "final url", "warning"})
+	for _, v := range results {
+		for _, vr := range v.APIResults {
+			n, ok := vr.Details[detailRedirects]
+			if !ok {
+				continue
+			}
+			t.AppendRow(table.Row{
+				v.Name, apiHeader(vr.API), n, vr.Details[detailFinalURL], yellow(vr.Details[detailRedirect]),
+			})
+		}
+	}
+	if t.Length() <= 0 {
+		return ""
+	}
+	return t.Render()
+}
+
+// detailedAPIs are the checks reporting a finding per endpoint in their
+// details, rendered in a table of their own
+var detailedAPIs = []string{"revocation", "headers"}
+
+// renderDetails lists the details of the results of the api, empty if
+// it was not checked
+func renderDetails(results []results, api string) string {
+	t := table.NewWriter()
+	t.SetTitle(api)
+	t.AppendHeader(table.Row{"validator", "endpoint", "result"})
+	for _, v := range results {
+		for _, vr := range v.APIResults {
+			if vr.API != api {
+				continue
+			}
+			keys := make([]string, 0, len(vr.Details))
+			for k := range vr.Details {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				t.AppendRow(table.Row{v.Name, k, vr.Details[k]})
+			}
+		}
+	}
+	if t.Length() <= 0 {
+		return ""
+	}
+	return t.Render()
+}
+
+func renderTimings(results []results) string {
+	t := table.NewWriter()
+	t.SetTitle("timings")
+	t.AppendHeader(table.Row{"validator", "api", "dns", "connect", "tls", "request"})
+	for _, v := range results {
+		for _, vr := range v.APIResults {
+			t.AppendRow(table.Row{
+				v.Name, apiHeader(vr.API),
+				formatDuration(vr.Timings.DNS), formatDuration(vr.Timings.Connect),
+				formatDuration(vr.Timings.TLS), formatDuration(vr.Timings.Request),
+			})
+		}
+	}
+	return t.Render()
+}
+
+// printPlan prints the checks which would be run on each validator
+func printPlan(validators []validator) {
+	t := table.NewWriter()
+	t.SetTitle(fmt.Sprintf("%d checks on %d validators", len(validators)*len(checks), len(validators)))
+	t.AppendHeader(table.Row{"validator", "api", "endpoint"})
+	for _, v := range validators {
+		for _, c := range checks {
+			t.AppendRow(table.Row{v.Name, apiHeader(c.name()), c.endpoint(v)})
+		}
+	}
+	fmt.Println(t.Render())
+}
+
+func renderSamples(results []results) string {
+	t := table.NewWriter()
+	t.SetTitle(fmt.Sprintf("latency over %d samples", samples))
+	t.AppendHeader(table.Row{"validator", "api", "min", "avg", "max", "p95", "failed"})
+	for _, v := range results {
+		for _, vr := range v.APIResults {
+			s := vr.Samples
+			if s == nil {
+				t.AppendRow(table.Row{v.Name, apiHeader(vr.API), "-", "-", "-", "-", samples})
+				continue
+			}
+			t.AppendRow(table.Row{
+				v.Name, apiHeader(vr.API),
+				formatDuration(s.Min), formatDuration(s.Avg), formatDuration(s.Max), formatDuration(s.P95), s.Failed,
+			})
+		}
+	}
+	return t.Render()
+}
+
+// detail returns an information reported by an API, or - if missing
+func detail(resMap map[string]aPIResult, api, key string) string {
+	if v, ok := resMap[api].Details[key]; ok && len(v) > 0 {
+		return v
+	}
+	return "-"
+}
+
+// cdns returns the CDNs detected in front of the APIs of a validator,
+// or - if none
+func cdns(res []aPIResult) string {
+	var names []string
+	for _, r := range res {
+		if cdn, ok := r.Details[detailCDN]; ok && !slices.Contains(names, cdn) {
+			names = append(names, cdn)
+		}
+	}
+	if len(names) <= 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+// resultAPIs returns the APIs present in the results, in the order
+// they were checked
+func resultAPIs(res []results) []string {
+	apis := []string{}
+	seen := map[string]bool{}
+	for _, v := range res {
+		for _, vr := range v.APIResults {
+			if !seen[vr.API] {
+				seen[vr.API] = true
+				apis = append(apis, vr.API)
+			}
+		}
+	}
+	return apis
+}
+
+// renderSummary builds the per API statistics table, latencies only
+// account for successful checks
+func renderSummary(res []results) string {
+	t := table.NewWriter()
+	t.SetTitle(fmt.Sprintf("%v validators checked", len(res)))
+	t.AppendHeader(table.Row{"api", "healthy", "degraded", "down", "avg", "p95"})
+
+	for _, api := range resultAPIs(res) {
+		var healthy, degraded, down int
+		durations := []time.Duration{}
+		for _, v := range res {
+			for _, vr := range v.APIResults {
+				if vr.API != api {
+					continue
+				}
+				switch apiStatus(vr) {
+				case apiStatusOK:
+					healthy++
+				case apiStatusError:
+					down++
+				default:
+					degraded++
+				}
+				if len(vr.Error) <= 0 {
+					durations = append(durations, vr.TimeTaken)
+				}
+			}
+		}
+		t.AppendRow(table.Row{
+			api, healthy, degraded, down,
+			formatDuration(average(durations)),
+			formatDuration(percentile(durations, 95)),
+		})
+	}
+
+	return t.Render()
+}
+
+func printJSON(v any) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		fatalConfig("could not format output: %v", err)
+	}
+	fmt.Printf("%v\n", string(buf))
+}
+
+// printCheckmk writes one Checkmk local check line per validator API:
+// <status> <service> <perfdata> <summary>
+func printCheckmk(results []results) {
+	for _, v := range results {
+		for _, vr := range v.APIResults {
+			var status int
+			var summary string
+			switch apiStatus(vr) {
+			case apiStatusOK:
+				status, summary = 0, fmt.Sprintf("OK - %v responded in %v", vr.API, vr.TimeTaken)
+			case apiStatusWarning:
+				status, summary = 1, fmt.Sprintf("WARN - %v slow, responded in %v", vr.API, vr.TimeTaken)
+			case apiStatusCritical:
+				status, summary = 2, fmt.Sprintf("CRIT - %v very slow, responded in %v", vr.API, vr.TimeTaken)
+			default:
+				status, summary = 2, fmt.Sprintf("CRIT - %v: %v", vr.API, strings.ReplaceAll(vr.Error, "\n", " "))
+			}
+			fmt.Printf("%d vega_%v_%v response_time=%f;%f;%f %v\n",
+				status, v.Name, vr.API, vr.TimeTaken.Seconds(),
+				warnThresholds.get(vr.API).Seconds(), critThresholds.get(vr.API).Seconds(), summary)
+		}
+	}
+}
+
+func renderDiff(changes []diffEntry) string {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	t := table.NewWriter()
+	t.SetTitle("changes")
+	t.AppendHeader(table.Row{"validator", "api", "change", "before", "after", "error"})
+	for _, v := range changes {
+		change := v.Change
+		switch v.Change {
+		case changeFailed:
+			change = red(change)
+		case changeRecovered:
+			change = green(change)
+		case changeRegressed, changeBaseline:
+			change = yellow(change)
+		}
+		t.AppendRow(table.Row{
+			v.Name, v.API, change,
+			fmt.Sprintf("%.2fms", v.BeforeMS),
+			fmt.Sprintf("%.2fms", v.AfterMS),
+			v.Error,
+		})
+	}
+	return t.Render()
+}
+
+// printTAP writes the results following the Test Anything Protocol,
+// one test point per validator API
+func printTAP(results []results) {
+	var total int
+	for _, v := range results {
+		total += len(v.APIResults)
+	}
+
+	fmt.Println("TAP version 13")
+	fmt.Printf("1..%d\n", total)
+
+	var n int
+	for _, v := range results {
+		for _, vr := range v.APIResults {
+			n++
+			if len(vr.Error) <= 0 {
+				fmt.Printf("ok %d - %v %v # %v\n", n, v.Name, vr.API, vr.TimeTaken)
+				continue
+			}
+			msg, _ := json.Marshal(vr.Error)
+			fmt.Printf("not ok %d - %v %v\n", n, v.Name, vr.API)
+			fmt.Println("  ---")
+			fmt.Printf("  message: %s\n", msg)
+			fmt.Printf("  duration_ms: %v\n", toMS(vr.TimeTaken))
+			fmt.Println("  ...")
+		}
+	}
+}
+
+func coloredDuration(res aPIResult) string {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	switch apiStatus(res) {
+	case apiStatusOK:
+		return green(formatDuration(res.TimeTaken)) + attempts(res)
+	case apiStatusWarning:
+		return yellow(formatDuration(res.TimeTaken)) + attempts(res)
+	default:
+		return red(formatDuration(res.TimeTaken)) + attempts(res)
+	}
+}
+
+// emojiDuration renders a result with a status symbol instead of ANSI
+// colours, which survives being pasted in chat applications
+func emojiDuration(res aPIResult) string {
+	switch apiStatus(res) {
+	case apiStatusOK:
+		return fmt.Sprintf("✅ (%v)%v", formatDuration(res.TimeTaken), attempts(res))
+	case apiStatusError:
+		return fmt.Sprintf("❌ (%v)%v", formatDuration(res.TimeTaken), attempts(res))
+	default:
+		return fmt.Sprintf("⚠️ (%v)%v", formatDuration(res.TimeTaken), attempts(res))
+	}
+}
+
+// formatDuration renders a latency of the human tables in the unit of
+// --units, auto picking the unit of each value
+func formatDuration(d time.Duration) string {
+	switch units {
+	case "ms":
+		return fmt.Sprintf("%.1fms", toMS(d))
+	case "s":
+		return fmt.Sprintf("%.3fs", d.Seconds())
+	}
+	// keep 3 or 4 significant digits, the sub-millisecond part of a
+	// slow check being noise
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+// attempts returns the number of attempts of a retried check
+func attempts(res aPIResult) string {
+	if res.Attempts <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" x%d", res.Attempts)
+}
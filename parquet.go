@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetRowGroupSize bounds the rows buffered by the export before
+// they are flushed as a row group
+const parquetRowGroupSize = 8 * 1024 * 1024
+
+// parquetRow is a row of the parquet export, the columns being the ones
+// of the csv export
+type parquetRow struct {
+	RunID     int64   `parquet:"name=run_id, type=INT64"`
+	StartedAt int64   `parquet:"name=started_at, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Network   string  `parquet:"name=network, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Validator string  `parquet:"name=validator, type=BYTE_ARRAY, convertedtype=UTF8"`
+	API       string  `parquet:"name=api, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Status    string  `parquet:"name=status, type=BYTE_ARRAY, convertedtype=UTF8"`
+	LatencyMS float64 `parquet:"name=latency_ms, type=DOUBLE"`
+	DNSMS     float64 `parquet:"name=dns_ms, type=DOUBLE"`
+	ConnectMS float64 `parquet:"name=connect_ms, type=DOUBLE"`
+	TLSMS     float64 `parquet:"name=tls_ms, type=DOUBLE"`
+	RequestMS float64 `parquet:"name=request_ms, type=DOUBLE"`
+	Attempts  int64   `parquet:"name=attempts, type=INT64"`
+	Error     string  `parquet:"name=error, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// exportParquet writes the results as a snappy compressed parquet file,
+// flushing a row group every parquetRowGroupSize bytes
+func exportParquet(w io.Writer, network string, res []storedResult) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(parquetRow), 1)
+	if err != nil {
+		return err
+	}
+	pw.RowGroupSize = parquetRowGroupSize
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	for _, r := range res {
+		err := pw.Write(parquetRow{
+			RunID:     r.RunID,
+			StartedAt: r.StartedAt.UnixMilli(),
+			Network:   network,
+			Validator: r.Validator,
+			API:       r.API,
+			Status:    r.Status,
+			LatencyMS: r.LatencyMS,
+			DNSMS:     r.DNSMS,
+			ConnectMS: r.ConnectMS,
+			TLSMS:     r.TLSMS,
+			RequestMS: r.RequestMS,
+			Attempts:  int64(r.Attempts),
+			Error:     r.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return pw.WriteStop()
+}
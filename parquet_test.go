@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// TestExportParquetRoundTrip reads the history export back with a
+// parquet reader
+func TestExportParquetRoundTrip(t *testing.T) {
+	startedAt := time.UnixMilli(1700000000000)
+	var res []storedResult
+	for i := 0; i < 20; i++ {
+		r := storedResult{
+			RunID:     int64(i / 2),
+			StartedAt: startedAt.Add(time.Duration(i) * time.Minute),
+			Validator: "validator " + string(rune('a'+i%2)),
+			API:       "rest",
+			Status:    apiStatusOK,
+			LatencyMS: 10.5 + float64(i),
+			DNSMS:     1,
+			ConnectMS: 2,
+			TLSMS:     3,
+			RequestMS: 4.5,
+			Attempts:  1,
+		}
+		if i%5 == 0 {
+			r.Status = apiStatusError
+			r.Error = "connection refused"
+		}
+		res = append(res, r)
+	}
+
+	var buf bytes.Buffer
+	if err := exportParquet(&buf, "testnet", res); err != nil {
+		t.Fatal(err)
+	}
+	file, err := buffer.NewBufferFile(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := reader.NewParquetReader(file, new(parquetRow), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.ReadStop()
+	if n := pr.GetNumRows(); n != int64(len(res)) {
+		t.Fatalf("%d rows, expected %d", n, len(res))
+	}
+	rows := make([]parquetRow, len(res))
+	if err := pr.Read(&rows); err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range res {
+		expected := parquetRow{
+			RunID: r.RunID, StartedAt: r.StartedAt.UnixMilli(), Network: "testnet",
+			Validator: r.Validator, API: r.API, Status: r.Status,
+			LatencyMS: r.LatencyMS, DNSMS: r.DNSMS, ConnectMS: r.ConnectMS, TLSMS: r.TLSMS, RequestMS: r.RequestMS,
+			Attempts: int64(r.Attempts), Error: r.Error,
+		}
+		if rows[i] != expected {
+			t.Errorf("row %d is %+v, expected %+v", i, rows[i], expected)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// pick enables the interactive selection of the validators to check
+var pick bool
+
+// errPickCancelled is returned when the selection is left with esc
+var errPickCancelled = errors.New("selection cancelled")
+
+// pickValidators lets the user select validators in the terminal,
+// typing filters them by fuzzy matching their names
+func pickValidators(validators []validator) ([]validator, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, errors.New("--pick requires a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer term.Restore(fd, state)
+	defer fmt.Print(clearScreen)
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	var (
+		query    string
+		cursor   int
+		selected = make([]bool, len(validators))
+	)
+	for {
+		visible := fuzzyFilter(validators, query)
+		if cursor >= len(visible) {
+			cursor = max(len(visible)-1, 0)
+		}
+		renderPicker(validators, visible, selected, query, cursor)
+
+		k, ok := <-keys
+		if !ok {
+			return nil, errPickCancelled
+		}
+		switch k {
+		case "esc", "ctrl-c":
+			return nil, errPickCancelled
+		case "up":
+			if cursor > 0 {
+				cursor--
+			}
+		case "down":
+			if cursor < len(visible)-1 {
+				cursor++
+			}
+		case " ", "\t":
+			if len(visible) > 0 {
+				selected[visible[cursor]] = !selected[visible[cursor]]
+			}
+		case "\x7f", "\b":
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+		case "enter":
+			var picked []validator
+			for i, v := range validators {
+				if selected[i] {
+					picked = append(picked, v)
+				}
+			}
+			// enter alone checks the validator under the cursor
+			if len(picked) <= 0 && len(visible) > 0 {
+				picked = append(picked, validators[visible[cursor]])
+			}
+			if len(picked) > 0 {
+				return picked, nil
+			}
+		default:
+			if len(k) == 1 && k[0] >= 0x20 && k[0] < 0x7f {
+				query += k
+			}
+		}
+	}
+}
+
+// fuzzyFilter returns the indices of the validators whose name holds
+// the characters of the query in order
+func fuzzyFilter(validators []validator, query string) []int {
+	query = strings.ToLower(query)
+	var matched []int
+	for i, v := range validators {
+		name, j := strings.ToLower(v.Name), 0
+		for _, r := range name {
+			if j < len(query) && rune(query[j]) == r {
+				j++
+			}
+		}
+		if j == len(query) {
+			matched = append(matched, i)
+		}
+	}
+	return matched
+}
+
+func renderPicker(validators []validator, visible []int, selected []bool, query string, cursor int) {
+	var b strings.Builder
+	var n int
+	for _, s := range selected {
+		if s {
+			n++
+		}
+	}
+	fmt.Fprintf(&b, "select the validators to check (%d selected)\n\n> %v\n\n", n, query)
+	for i, idx := range visible {
+		pointer, mark := " ", "[ ]"
+		if i == cursor {
+			pointer = ">"
+		}
+		if selected[idx] {
+			mark = "[x]"
+		}
+		fmt.Fprintf(&b, "%v %v %v\n", pointer, mark, validators[idx].Name)
+	}
+	if len(visible) <= 0 {
+		b.WriteString("  no match\n")
+	}
+	b.WriteString("\ntype to filter  ↑/↓ move  space select  enter check  esc cancel\n")
+
+	// the terminal is in raw mode, lines need a carriage return
+	fmt.Print(clearScreen + strings.ReplaceAll(b.String(), "\n", "\r\n"))
+}
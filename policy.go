@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// exitPolicy describes which results make the process exit with a
+// non zero status
+type exitPolicy struct {
+	failOnAnyError   bool
+	failIfDown       int
+	failIfSlowerThan time.Duration
+}
+
+func (p exitPolicy) isSet() bool {
+	return p.failOnAnyError || p.failIfDown > 0 || p.failIfSlowerThan > 0
+}
+
+// allDown returns whether every validator of the run is down
+func allDown(res []results) bool {
+	for _, v := range res {
+		if v.status() != "down" {
+			return false
+		}
+	}
+	return len(res) > 0
+}
+
+// check returns a non nil error describing the first rule of the
+// policy broken by the results
+func (p exitPolicy) check(res []results) error {
+	var down int
+	for _, v := range res {
+		if v.status() == "down" {
+			down++
+		}
+		for _, vr := range v.APIResults {
+			if p.failOnAnyError && len(vr.Error) > 0 {
+				return fmt.Errorf("%v %v failed: %v", v.Name, vr.API, vr.Error)
+			}
+			if p.failIfSlowerThan > 0 && len(vr.Error) <= 0 && vr.TimeTaken > p.failIfSlowerThan {
+				return fmt.Errorf("%v %v took %v, more than %v", v.Name, vr.API, vr.TimeTaken, p.failIfSlowerThan)
+			}
+		}
+	}
+
+	if p.failIfDown > 0 && down >= p.failIfDown {
+		return fmt.Errorf("%v validators down, limit is %v", down, p.failIfDown)
+	}
+
+	return nil
+}
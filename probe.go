@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var probeDatanode bool
+
+func newProbeCmd() *cobra.Command {
+	var target validator
+	cmd := &cobra.Command{
+		Use:   "probe",
+		Short: "Check a single node without configuration, exiting with 0 if healthy and 1 otherwise (docker HEALTHCHECK, kubernetes exec probes)",
+		Example: `  check_validator_setup probe --grpc localhost:3002
+  check_validator_setup probe --grpc localhost:3007 --datanode --rest https://localhost:3008 --gql https://localhost:3008/graphql`,
+		Args: cobra.NoArgs,
+		PreRunE: func(*cobra.Command, []string) error {
+			if len(target.GRPC) <= 0 && len(target.REST) <= 0 && len(target.GQL) <= 0 && len(target.CoreREST) <= 0 {
+				return fmt.Errorf("at least one of --grpc, --rest, --gql or --core-rest is required")
+			}
+			if probeDatanode && len(target.GRPC) <= 0 {
+				return fmt.Errorf("--datanode requires --grpc")
+			}
+			return nil
+		},
+		Run: func(*cobra.Command, []string) {
+			target.Name = "probe"
+			if !probe(target) {
+				os.Exit(exitFailed)
+			}
+		},
+	}
+	fs := cmd.Flags()
+	fs.StringVar(&target.GRPC, "grpc", "", "grpc address of the node (e.g: localhost:3002)")
+	fs.StringVar(&target.REST, "rest", "", "rest url of the node (e.g: https://localhost:3008)")
+	fs.StringVar(&target.GQL, "gql", "", "graphql url of the node (e.g: https://localhost:3008/graphql)")
+	fs.StringVar(&target.CoreREST, "core-rest", "", "url of the rest gateway of the core node (e.g: https://localhost:3003)")
+	fs.BoolVar(&probeDatanode, "datanode", false, "also check the data-node api on the grpc address")
+	fs.DurationVar(&timeout, "timeout", 2*time.Second, "timeout of each check")
+	fs.IntVar(&retries, "retries", 0, "number of times a failed check is retried")
+	addTransportFlags(fs)
+	fs.DurationVar(&retryBackoff, "retry-backoff", 200*time.Millisecond, "delay before the first retry, doubled on each retry")
+	return cmd
+}
+
+// probe checks the apis of the target and prints a single line
+// summarizing them, returning whether all of them are healthy
+func probe(target validator) bool {
+	var names []string
+	if len(target.GRPC) > 0 {
+		names = append(names, "core")
+		if probeDatanode {
+			names = append(names, "datanode")
+		}
+	}
+	if len(target.REST) > 0 {
+		names = append(names, "rest")
+	}
+	if len(target.GQL) > 0 {
+		names = append(names, "gql")
+	}
+	if len(target.CoreREST) > 0 {
+		names = append(names, "corerest")
+	}
+
+	ctx, cancel := runContext()
+	defer cancel()
+	res := runChecks(ctx, []validator{target}, selectChecks(names), nil)
+
+	var (
+		healthy = true
+		parts   []string
+	)
+	for _, r := range res[0].APIResults {
+		if len(r.Error) > 0 {
+			healthy = false
+			parts = append(parts, fmt.Sprintf("%v=%v", r.API, r.Error))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%v=%v", r.API, r.TimeTaken.Round(time.Millisecond)))
+	}
+	status := "ok"
+	if !healthy {
+		status = "fail"
+	}
+	fmt.Printf("%v %v\n", status, strings.Join(parts, " "))
+	return healthy
+}
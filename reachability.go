@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	reachable      = "ok"
+	pingNotAllowed = "not permitted"
+
+	// detailPingWarn lists the hosts which did not answer the ping, the
+	// underscore keeping it apart from the host names of the details
+	detailPingWarn = "ping_warning"
+)
+
+var (
+	precheck bool
+
+	errPingNotAllowed = errors.New("icmp sockets are not permitted")
+)
+
+// reachabilityChecks returns the checks of the hosts and ports of the
+// api checks, run before them with --precheck
+func reachabilityChecks(checks []checker) []checker {
+	return []checker{pingCheck{checks}, tcpCheck{checks}}
+}
+
+// runSize returns the number of results of a run
+func runSize(validators []validator, checks []checker) int {
+	n := len(checks)
+	if precheck {
+		n += len(reachabilityChecks(checks))
+	}
+	return len(validators) * n
+}
+
+// endpointHostPort returns the host and port an endpoint connects to,
+// false for endpoints which are not network addresses
+func endpointHostPort(endpoint string) (string, bool) {
+	if u, err := url.Parse(endpoint); err == nil && len(u.Host) > 0 {
+		port := u.Port()
+		switch {
+		case len(port) > 0:
+		case u.Scheme == "http":
+			port = "80"
+		case u.Scheme == "https":
+			port = "443"
+		default:
+			return "", false
+		}
+		return net.JoinHostPort(u.Hostname(), port), true
+	}
+	if _, _, err := net.SplitHostPort(endpoint); err == nil {
+		return endpoint, true
+	}
+	return "", false
+}
+
+// endpoints returns the distinct host:port of the api checks of a
+// validator, in check order
+func endpoints(checks []checker, v validator) []string {
+	var hps []string
+	for _, c := range checks {
+		if hp, ok := endpointHostPort(c.endpoint(v)); ok && !slices.Contains(hps, hp) {
+			hps = append(hps, hp)
+		}
+	}
+	return hps
+}
+
+// probeAll runs the probe on each target concurrently, returning the
+// state of each target and the failures
+func probeAll(targets []string, probe func(string) (time.Duration, error)) (map[string]string, []string, time.Duration) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		details  = map[string]string{}
+		failures []string
+		slowest  time.Duration
+	)
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			d, err := probe(target)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, errPingNotAllowed):
+				details[target] = pingNotAllowed
+			case err != nil:
+				details[target] = err.Error()
+				failures = append(failures, target)
+			default:
+				details[target] = reachable
+				if d > slowest {
+					slowest = d
+				}
+			}
+		}(target)
+	}
+	wg.Wait()
+	return details, failures, slowest
+}
+
+// tcpCheck connects to each host and port of the api checks
+type tcpCheck struct {
+	checks []checker
+}
+
+func (c tcpCheck) name() string {
+	return "tcp"
+}
+
+func (c tcpCheck) endpoint(v validator) string {
+	return strings.Join(endpoints(c.checks, v), ",")
+}
+
+func (c tcpCheck) run(ctx context.Context, v validator) (timings, map[string]string, error) {
+	ctx, cancel := connectContext(ctx)
+	defer cancel()
+	ctx, cancelCheck := context.WithTimeout(ctx, timeout)
+	defer cancelCheck()
+
+	details, failures, slowest := probeAll(endpoints(c.checks, v), func(hp string) (time.Duration, error) {
+		start := time.Now()
+		conn, err := dialContext(ctx, "tcp", hp)
+		if err != nil {
+			return 0, err
+		}
+		conn.Close()
+		return time.Since(start), nil
+	})
+	t := timings{Connect: slowest}
+	if len(failures) > 0 {
+		return t, details, fmt.Errorf("could not connect to %v", strings.Join(failures, ", "))
+	}
+	return t, details, nil
+}
+
+// pingCheck sends an ICMP echo request to each host of the api checks,
+// where ICMP sockets are permitted, warning about the unanswered ones
+// rather than failing
+type pingCheck struct {
+	checks []checker
+}
+
+func (c pingCheck) name() string {
+	return "ping"
+}
+
+func (c pingCheck) hosts(v validator) []string {
+	var hosts []string
+	for _, hp := range endpoints(c.checks, v) {
+		host, _, _ := net.SplitHostPort(hp)
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func (c pingCheck) endpoint(v validator) string {
+	return strings.Join(c.hosts(v), ",")
+}
+
+func (c pingCheck) run(ctx context.Context, v validator) (timings, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	details, failures, slowest := probeAll(c.hosts(v), func(host string) (time.Duration, error) {
+		return ping(ctx, host)
+	})
+	// many hosts filter ICMP, the unanswered hosts are only a warning,
+	// the tcp check deciding whether a host is reachable
+	if len(failures) > 0 {
+		details[detailPingWarn] = "no echo reply from " + strings.Join(failures, ", ")
+	}
+	return timings{Request: slowest}, details, nil
+}
+
+// ping sends an ICMP echo request to the host, returning the round trip
+// time. Unprivileged ICMP sockets are used if available, falling back
+// to raw sockets, errPingNotAllowed is returned if neither is.
+func ping(ctx context.Context, host string) (time.Duration, error) {
+	ips, err := resolver.LookupIP(ctx, "ip"+ipFamily(), host)
+	if err != nil {
+		return 0, err
+	}
+	ip := ips[0]
+
+	var (
+		network, rawNetwork, local string
+		proto                      int
+		typ, replyType             icmp.Type
+	)
+	if ip.To4() != nil {
+		network, rawNetwork, local, proto = "udp4", "ip4:icmp", "0.0.0.0", 1
+		typ, replyType = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	} else {
+		network, rawNetwork, local, proto = "udp6", "ip6:ipv6-icmp", "::", 58
+		typ, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	if localIP != nil {
+		local = localIP.String()
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	conn, err := icmp.ListenPacket(network, local)
+	if err != nil {
+		dst = &net.IPAddr{IP: ip}
+		conn, err = icmp.ListenPacket(rawNetwork, local)
+		if err != nil {
+			return 0, errPingNotAllowed
+		}
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	msg := icmp.Message{
+		Type: typ,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: 1, Data: []byte("check_validator_setup")},
+	}
+	buf, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if _, err := conn.WriteTo(buf, dst); err != nil {
+		return 0, err
+	}
+	reply := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(reply)
+		if err != nil {
+			return 0, err
+		}
+		// raw sockets receive the replies to every ping of the process
+		if peer.String() != dst.String() {
+			continue
+		}
+		m, err := icmp.ParseMessage(proto, reply[:n])
+		if err == nil && m.Type == replyType {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// attributeFailure prefixes the error of a failed api check with the
+// cause found by the tcp check: the host is down when none of its ports
+// accepts connections, the port unreachable or the service itself is
+// failing, ping being too often filtered to tell
+func attributeFailure(reachability []aPIResult, endpoint, err string) string {
+	hp, ok := endpointHostPort(endpoint)
+	if !ok || len(reachability) < 2 {
+		return err
+	}
+	tcp := reachability[1].Details
+	switch {
+	case len(tcp[hp]) <= 0:
+		return err
+	case tcp[hp] == reachable:
+		return "service error: " + err
+	}
+
+	host, _, _ := net.SplitHostPort(hp)
+	for target, state := range tcp {
+		if h, _, _ := net.SplitHostPort(target); h == host && state == reachable {
+			return "port unreachable: " + err
+		}
+	}
+	return "host down: " + err
+}
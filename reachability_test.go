@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+// TestAttributeFailure checks the failures are attributed from the tcp
+// check alone, an unanswered ping being only a warning
+func TestAttributeFailure(t *testing.T) {
+	reachability := []aPIResult{
+		{API: "ping", Details: map[string]string{"a": "i/o timeout", "b": "i/o timeout", detailPingWarn: "no echo reply from a, b"}},
+		{API: "tcp", Details: map[string]string{"a:443": reachable, "a:3002": "connection refused", "b:443": "connection refused"}},
+	}
+	for endpoint, prefix := range map[string]string{
+		"https://a":   "service error: ",
+		"a:3002":      "port unreachable: ",
+		"https://b":   "host down: ",
+		"https://c:1": "",
+	} {
+		got := attributeFailure(reachability, endpoint, "failed")
+		if got != prefix+"failed" {
+			t.Errorf("%v: %q, expected %q", endpoint, got, prefix+"failed")
+		}
+	}
+}
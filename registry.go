@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// checker is a probe run on every validator, reported as one of the
+// APIs of its results
+type checker interface {
+	// name identifies the check in the results, the configuration and
+	// on the command line
+	name() string
+	// endpoint returns what the check probes on the validator
+	endpoint(v validator) string
+	run(ctx context.Context, v validator) (timings, map[string]string, error)
+}
+
+// profile is the thoroughness of a run, each profile running the
+// checks of the lighter ones too
+type profile int
+
+const (
+	profileQuick profile = iota
+	profileStandard
+	profileDeep
+)
+
+var profileNames = []string{"quick", "standard", "deep"}
+
+func (p profile) String() string {
+	return profileNames[p]
+}
+
+func parseProfile(s string) (profile, error) {
+	for i, n := range profileNames {
+		if strings.EqualFold(s, n) {
+			return profile(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid profile: %v [%v]", s, strings.Join(profileNames, "|"))
+}
+
+// checkOrder is the order the built-in checks are run and displayed in,
+// whatever the order of the init functions registering them
+var checkOrder = []string{
+	"core", "datanode", "rest", "gql",
+	"compat", "tendermint", "corerest", "archival", "freshness", "gqlcomplexity",
+	"rewards", "transfers", "ledger",
+	"openapi", "oracle", "revocation", "headers", "signing", "votingpower",
+}
+
+var (
+	// registry holds the available checks sorted by checkOrder, the
+	// checks missing from it, e.g: the external checks, following in
+	// registration order
+	registry []checker
+
+	// checkProfiles holds the lightest profile running each check
+	checkProfiles = map[string]profile{}
+
+	// checks holds the checks enabled for the run
+	checks []checker
+
+	// apiFilter restricts the checks of the run to the named ones
+	apiFilter []string
+)
+
+// register makes the check available to the runs of profile p or a
+// deeper one, it is meant to be called from the init function of the
+// file implementing the check
+func register(c checker, p profile) {
+	for _, r := range registry {
+		if r.name() == c.name() {
+			fatalConfig("check registered twice: %v", c.name())
+		}
+	}
+	i := len(registry)
+	for i > 0 && checkRank(registry[i-1].name()) > checkRank(c.name()) {
+		i--
+	}
+	registry = slices.Insert(registry, i, c)
+	checkProfiles[c.name()] = p
+}
+
+// checkRank returns the position of the check in checkOrder, after all
+// of them if it is not listed
+func checkRank(name string) int {
+	if i := slices.Index(checkOrder, name); i >= 0 {
+		return i
+	}
+	return len(checkOrder)
+}
+
+// checkNames returns the names of the registered checks
+func checkNames() []string {
+	names := make([]string, 0, len(registry))
+	for _, c := range registry {
+		names = append(names, c.name())
+	}
+	return names
+}
+
+// selectChecks returns the checks named by --checks, those of
+// --profile, or those named by the configuration, in that order of
+// precedence, those of the standard profile if none is set, restricted
+// to the ones named by --api, exiting if a name does not match a
+// registered check
+func selectChecks(names []string) []checker {
+	selected := selectedChecks(names)
+	if len(apiFilter) <= 0 {
+		return selected
+	}
+
+	validateCheckNames(apiFilter)
+	var filtered []checker
+	for _, c := range selected {
+		for _, n := range apiFilter {
+			if c.name() == strings.ToLower(strings.TrimSpace(n)) {
+				filtered = append(filtered, c)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// validateCheckNames exits if a name does not match a registered check
+func validateCheckNames(names []string) {
+	for _, n := range names {
+		if checkIndex(strings.ToLower(strings.TrimSpace(n))) < 0 {
+			fatalConfig("not an existing check: %v (available: %v)", n, strings.Join(checkNames(), ", "))
+		}
+	}
+}
+
+func selectedChecks(names []string) []checker {
+	if len(enabledChecks) > 0 {
+		names = enabledChecks
+	} else if len(profileName) > 0 {
+		p, err := parseProfile(profileName)
+		if err != nil {
+			fatalConfig("%v", err)
+		}
+		return profileChecks(p)
+	}
+	if len(names) <= 0 {
+		return profileChecks(profileStandard)
+	}
+
+	validateCheckNames(names)
+	enabled := map[string]bool{}
+	for _, n := range names {
+		enabled[strings.ToLower(strings.TrimSpace(n))] = true
+	}
+
+	var selected []checker
+	for _, c := range registry {
+		if enabled[c.name()] {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// profileChecks returns the checks run by the profile
+func profileChecks(p profile) []checker {
+	var selected []checker
+	for _, c := range registry {
+		if checkProfiles[c.name()] <= p {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// checkIndex returns the position of the check in the registry, -1 if
+// it is not registered
+func checkIndex(name string) int {
+	for i, c := range registry {
+		if c.name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// apiCheck is a checker probing one of the addresses of a validator
+type apiCheck struct {
+	api     string
+	address func(v validator) string
+	probe   func(ctx context.Context, address string) (timings, map[string]string, error)
+}
+
+func (c apiCheck) name() string {
+	return c.api
+}
+
+func (c apiCheck) endpoint(v validator) string {
+	return c.address(v)
+}
+
+func (c apiCheck) run(ctx context.Context, v validator) (timings, map[string]string, error) {
+	t, details, err := c.probe(ctx, c.address(v))
+	if details != nil {
+		addGeoDetails(details)
+	}
+	return t, details, err
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestRegistryOrder checks every built-in check is listed in checkOrder
+// and registered in its order
+func TestRegistryOrder(t *testing.T) {
+	if names := checkNames(); !slices.Equal(names, checkOrder) {
+		t.Errorf("registered checks %v, expected %v", names, checkOrder)
+	}
+}
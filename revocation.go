@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func init() {
+	register(revocationCheck{}, profileDeep)
+}
+
+const (
+	revocationGood    = "good"
+	revocationRevoked = "revoked"
+)
+
+// tlsEndpoints returns the distinct host:port of the apis of a
+// validator served over tls
+func tlsEndpoints(v validator) []string {
+	var hps []string
+	add := func(endpoint string) {
+		if hp, ok := endpointHostPort(endpoint); ok && !slices.Contains(hps, hp) {
+			hps = append(hps, hp)
+		}
+	}
+	if strings.HasPrefix(v.GRPC, "tls://") {
+		add(v.GRPC)
+	}
+	for _, endpoint := range []string{v.REST, v.GQL} {
+		if strings.HasPrefix(endpoint, "https://") {
+			add(endpoint)
+		}
+	}
+	return hps
+}
+
+// revocationCheck checks the revocation status of the certificates
+// served by the tls endpoints, from the stapled OCSP response, the OCSP
+// responder or the CRL of the issuer in that order
+type revocationCheck struct{}
+
+func (revocationCheck) name() string {
+	return "revocation"
+}
+
+func (revocationCheck) endpoint(v validator) string {
+	return strings.Join(tlsEndpoints(v), ",")
+}
+
+func (revocationCheck) run(ctx context.Context, v validator) (timings, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, httpCheckTimeout())
+	defer cancel()
+
+	var (
+		t       timings
+		details = map[string]string{}
+		revoked []string
+	)
+	for _, hp := range tlsEndpoints(v) {
+		start := time.Now()
+		status, err := revocationStatus(ctx, hp)
+		t.Request += time.Since(start)
+		if err != nil {
+			details[hp] = err.Error()
+			continue
+		}
+		details[hp] = status
+		if strings.HasPrefix(status, revocationRevoked) {
+			revoked = append(revoked, hp)
+		}
+	}
+	if len(revoked) > 0 {
+		return t, details, fmt.Errorf("revoked certificate served by %v", strings.Join(revoked, ", "))
+	}
+	return t, details, nil
+}
+
+// revocationStatus returns the revocation status of the certificate
+// served at the address, and how it was found
+func revocationStatus(ctx context.Context, hp string) (string, error) {
+	host, _, _ := net.SplitHostPort(hp)
+	conn, err := dialContext(ctx, "tcp", hp)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	cfg := clientTLS.Clone()
+	cfg.ServerName = serverName(ctx, host)
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return "", err
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) < 2 {
+		return "", errors.New("issuer certificate not served")
+	}
+	leaf, issuer := state.PeerCertificates[0], state.PeerCertificates[1]
+
+	if len(state.OCSPResponse) > 0 {
+		resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer)
+		if err != nil {
+			return "", fmt.Errorf("invalid stapled ocsp response: %w", err)
+		}
+		return ocspStatus(resp, "stapled ocsp"), nil
+	}
+	if len(leaf.OCSPServer) > 0 {
+		resp, err := queryOCSP(ctx, leaf.OCSPServer[0], leaf, issuer)
+		if err != nil {
+			return "", fmt.Errorf("ocsp responder: %w", err)
+		}
+		return ocspStatus(resp, "ocsp"), nil
+	}
+	if len(leaf.CRLDistributionPoints) > 0 {
+		return checkCRL(ctx, leaf.CRLDistributionPoints[0], leaf, issuer)
+	}
+	return "", errors.New("no ocsp responder nor crl to check the certificate against")
+}
+
+func ocspStatus(resp *ocsp.Response, source string) string {
+	switch resp.Status {
+	case ocsp.Good:
+		return fmt.Sprintf("%v (%v)", revocationGood, source)
+	case ocsp.Revoked:
+		return fmt.Sprintf("%v at %v (%v)", revocationRevoked, resp.RevokedAt.Format(time.RFC3339), source)
+	}
+	return fmt.Sprintf("unknown (%v)", source)
+}
+
+func queryOCSP(ctx context.Context, server string, leaf, issuer *x509.Certificate) (*ocsp.Response, error) {
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := fetch(ctx, http.MethodPost, server, "application/ocsp-request", req, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(body, leaf, issuer)
+}
+
+func checkCRL(ctx context.Context, point string, leaf, issuer *x509.Certificate) (string, error) {
+	if _, err := url.Parse(point); err != nil {
+		return "", err
+	}
+	body, err := fetch(ctx, http.MethodGet, point, "", nil, 32<<20)
+	if err != nil {
+		return "", fmt.Errorf("crl: %w", err)
+	}
+	crl, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid crl: %w", err)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return "", fmt.Errorf("invalid crl signature: %w", err)
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			return fmt.Sprintf("%v at %v (crl)", revocationRevoked, entry.RevocationTime.Format(time.RFC3339)), nil
+		}
+	}
+	return revocationGood + " (crl)", nil
+}
+
+// fetch sends a request to a certificate authority, returning the body
+// of the response up to limit bytes
+func fetch(ctx context.Context, method, url, contentType string, body []byte, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected http status code: %v", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
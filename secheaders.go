@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	register(securityHeadersCheck{}, profileDeep)
+}
+
+// securityHeaders are the hardening headers expected from a public http
+// gateway, with whether they only apply to https
+var securityHeaders = []struct {
+	name      string
+	httpsOnly bool
+}{
+	{"Strict-Transport-Security", true},
+	{"X-Content-Type-Options", false},
+	{"X-Frame-Options", false},
+	{"Content-Security-Policy", false},
+	{"Referrer-Policy", false},
+}
+
+// serverVersion matches Server headers disclosing the version of the
+// software, e.g: nginx/1.18.0
+var serverVersion = regexp.MustCompile(`/\d`)
+
+// securityHeadersCheck audits the hardening headers of the rest and gql
+// endpoints, it is informational and only fails if the endpoints could
+// not be reached
+type securityHeadersCheck struct{}
+
+func (securityHeadersCheck) name() string {
+	return "headers"
+}
+
+func (securityHeadersCheck) endpoint(v validator) string {
+	var endpoints []string
+	for _, e := range []string{v.REST, v.GQL} {
+		if len(e) > 0 {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return strings.Join(endpoints, ",")
+}
+
+func (securityHeadersCheck) run(ctx context.Context, v validator) (timings, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, httpCheckTimeout())
+	defer cancel()
+
+	var (
+		t       timings
+		details = map[string]string{}
+	)
+	audit := func(api string, req *http.Request, err error) error {
+		if err != nil {
+			return err
+		}
+		applyHeaders(req)
+		start := time.Now()
+		resp, err := httpClientFor(ctx).Do(req)
+		t.Request += time.Since(start)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		details[api] = auditHeaders(resp)
+		return nil
+	}
+
+	if len(v.REST) > 0 {
+		s, err := url.JoinPath(v.REST, "api/v2/info")
+		if err != nil {
+			return t, details, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s, nil)
+		if err := audit("rest", req, err); err != nil {
+			return t, details, err
+		}
+	}
+	if len(v.GQL) > 0 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.GQL, bytes.NewBufferString(gqlPayload))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if err := audit("gql", req, err); err != nil {
+			return t, details, err
+		}
+	}
+	return t, details, nil
+}
+
+// auditHeaders summarizes the hardening headers missing from the
+// response and the information it discloses
+func auditHeaders(resp *http.Response) string {
+	https := resp.Request.URL.Scheme == "https"
+	var missing []string
+	for _, h := range securityHeaders {
+		if h.httpsOnly && !https {
+			continue
+		}
+		if len(resp.Header.Get(h.name)) <= 0 {
+			missing = append(missing, strings.ToLower(h.name))
+		}
+	}
+
+	var findings []string
+	if len(missing) > 0 {
+		findings = append(findings, "missing "+strings.Join(missing, ", "))
+	}
+	if server := resp.Header.Get("Server"); serverVersion.MatchString(server) {
+		findings = append(findings, "server version disclosed: "+server)
+	}
+	if !https {
+		findings = append(findings, "served over plain http")
+	}
+	if len(findings) <= 0 {
+		return "ok"
+	}
+	return strings.Join(findings, "; ")
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// run is one execution of the checks over all the validators
+type run struct {
+	Timestamp time.Time
+	Results   []results
+}
+
+// runHistory keeps the most recent runs in memory
+type runHistory struct {
+	mu   sync.RWMutex
+	size int
+	runs []run
+}
+
+func newRunHistory(size int) *runHistory {
+	return &runHistory{size: size}
+}
+
+func (h *runHistory) add(r run) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.runs = append(h.runs, r)
+	if len(h.runs) > h.size {
+		h.runs = h.runs[len(h.runs)-h.size:]
+	}
+}
+
+// latest returns the most recent run, false if none completed yet
+func (h *runHistory) latest() (run, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.runs) <= 0 {
+		return run{}, false
+	}
+	return h.runs[len(h.runs)-1], true
+}
+
+// all returns the runs from the oldest to the most recent
+func (h *runHistory) all() []run {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return append([]run{}, h.runs...)
+}
+
+// serve runs the checks every interval, or on the schedules if any,
+// and exposes the results over http until the server fails
+func serve(addr, network string, validators []validator, notifiers []notifier, st *store, schedules []schedule) error {
+	history := newRunHistory(historySize)
+
+	// runs of different schedules do not overlap, each one updating
+	// the results of its own checks
+	var mu sync.Mutex
+	runOnce := func(checks []checker) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		startedAt := time.Now()
+		ctx, cancel := runContext()
+		res := runChecks(ctx, validators, checks, nil)
+		cancel()
+		slog.Info("checks completed", "duration", time.Since(startedAt), "checks", len(checks))
+
+		var changes []diffEntry
+		previous, ok := history.latest()
+		if ok {
+			changes = diffResults(newReport(network, previous.Timestamp, previous.Results, nil), res, regressionFactor)
+		}
+		regressions, err := baselineRegressions(st, network, res)
+		if err != nil {
+			slog.Error("could not compare with the baseline", "error", err)
+		}
+		changes = append(changes, regressions...)
+		// only the checks which ran are stored
+		if st != nil {
+			if _, err := st.saveRun(newReport(network, startedAt, res, changes)); err != nil {
+				slog.Error("could not store results", "error", err)
+			}
+			applyRetention(st)
+		}
+		if ok {
+			res = mergeResults(previous.Results, res)
+		}
+		history.add(run{Timestamp: startedAt, Results: res})
+		pushMetrics(network, run{Timestamp: startedAt, Results: res})
+
+		notifyAll(notifiers, notification{
+			Network:   network,
+			Timestamp: startedAt,
+			Results:   res,
+			Changes:   alertedChanges(changes),
+			FirstRun:  !ok,
+		})
+	}
+
+	if len(schedules) <= 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				runOnce(checks)
+				<-ticker.C
+			}
+		}()
+	}
+	for _, s := range schedules {
+		go func(s schedule) {
+			for {
+				next := s.cron.next(time.Now())
+				if next.IsZero() {
+					slog.Error("schedule never runs", "profile", s.profile)
+					return
+				}
+				slog.Debug("next scheduled run", "profile", s.profile, "at", next)
+				time.Sleep(time.Until(next))
+				runOnce(s.checks)
+			}
+		}(s)
+	}
+
+	regions := newRegionReports()
+	mux := http.NewServeMux()
+	registerMetrics(mux, network, history, regions)
+	registerAPI(mux, network, history)
+	registerAgents(mux, network, history, regions)
+	registerGrafana(mux, history)
+	registerHealth(mux, history)
+	registerDashboard(mux, network, st)
+	if enablePprof {
+		registerPprof(mux)
+	}
+
+	slog.Info("serving results", "address", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// mergeResults returns the previous results updated with those of the
+// checks which ran since, in registry order
+func mergeResults(prev, res []results) []results {
+	latest := map[string][]aPIResult{}
+	for _, v := range res {
+		latest[v.Name] = v.APIResults
+	}
+
+	merged := make([]results, 0, len(prev))
+	for _, v := range prev {
+		byAPI := map[string]aPIResult{}
+		for _, r := range v.APIResults {
+			byAPI[r.API] = r
+		}
+		for _, r := range latest[v.Name] {
+			byAPI[r.API] = r
+		}
+		apiResults := make([]aPIResult, 0, len(byAPI))
+		for _, r := range byAPI {
+			apiResults = append(apiResults, r)
+		}
+		sort.Slice(apiResults, func(i, j int) bool {
+			return checkIndex(apiResults[i].API) < checkIndex(apiResults[j].API)
+		})
+		merged = append(merged, results{Name: v.Name, APIResults: apiResults})
+	}
+	return merged
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(apiCheck{"signing", func(v validator) string { return v.Tendermint }, checkSigning}, profileDeep)
+}
+
+const detailSigned = "signed"
+
+// blockIDFlagCommit marks the commit signatures of the validators
+// which voted for the block
+const blockIDFlagCommit = 2
+
+var (
+	signingWindow   int
+	maxMissedBlocks int
+)
+
+// tendermintCommit is the part of the response of the CometBFT /commit
+// rpc listing the signatures of a block
+type tendermintCommit struct {
+	Result struct {
+		SignedHeader struct {
+			Commit struct {
+				Signatures []struct {
+					BlockIDFlag      int    `json:"block_id_flag"`
+					ValidatorAddress string `json:"validator_address"`
+				} `json:"signatures"`
+			} `json:"commit"`
+		} `json:"signed_header"`
+	} `json:"result"`
+}
+
+// checkSigning counts the blocks among the last --signing-window ones
+// signed by the node, failing if it missed more than
+// --max-missed-blocks of them
+func checkSigning(ctx context.Context, address string) (timings, map[string]string, error) {
+	if len(address) <= 0 {
+		return notConfigured()
+	}
+	var status tendermintStatus
+	t, details, err := tendermintRPC(ctx, address, "status", nil, &status)
+	if err != nil {
+		return t, details, err
+	}
+	info := status.Result.ValidatorInfo
+	if info.VotingPower == "" || info.VotingPower == "0" {
+		return t, details, errors.New("node is not in the validator set")
+	}
+	height, err := strconv.ParseInt(status.Result.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return t, details, fmt.Errorf("invalid block height: %q", status.Result.SyncInfo.LatestBlockHeight)
+	}
+
+	var blocks, signed int
+	for h := height; h > 0 && blocks < signingWindow; h-- {
+		var commit tendermintCommit
+		ct, _, err := tendermintRPC(ctx, address, "commit", url.Values{"height": {strconv.FormatInt(h, 10)}}, &commit)
+		t.Request += ct.Request
+		if err != nil {
+			return t, details, fmt.Errorf("could not get the commit of block %d: %w", h, err)
+		}
+		blocks++
+		for _, sig := range commit.Result.SignedHeader.Commit.Signatures {
+			if sig.BlockIDFlag == blockIDFlagCommit && strings.EqualFold(sig.ValidatorAddress, info.Address) {
+				signed++
+				break
+			}
+		}
+	}
+
+	details[detailSigned] = fmt.Sprintf("%d/%d", signed, blocks)
+	if missed := blocks - signed; missed > maxMissedBlocks {
+		return t, details, fmt.Errorf("missed %d of the last %d blocks", missed, blocks)
+	}
+	return t, details, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type slackConfig struct {
+	WebhookURL string `json:"webhook_url"`
+	// OnlyChanges only posts when the status of an API changed since
+	// the previous run
+	OnlyChanges bool `json:"only_changes"`
+}
+
+type slackNotifier struct {
+	cfg slackConfig
+}
+
+func (s *slackNotifier) name() string { return "slack" }
+
+func (s *slackNotifier) notify(ctx context.Context, n notification) error {
+	if s.cfg.OnlyChanges && len(n.Changes) <= 0 {
+		return nil
+	}
+
+	return postJSON(ctx, s.cfg.WebhookURL, nil, map[string]string{"text": slackMessage(n)})
+}
+
+// slackMessage formats the run using slack mrkdwn
+func slackMessage(n notification) string {
+	counts := map[string]int{}
+	for _, v := range n.Results {
+		counts[v.status()]++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%v validators*: %d up, %d degraded, %d down\n",
+		n.Network, counts["up"], counts["degraded"], counts["down"])
+
+	if alerts := n.alerts(); len(alerts) > 0 {
+		b.WriteString("\n*Network alerts*\n")
+		for _, a := range alerts {
+			fmt.Fprintf(&b, ":rotating_light: %v\n", a)
+		}
+	}
+
+	if len(n.Changes) > 0 {
+		b.WriteString("\n*Changes since previous run*\n")
+		for _, c := range n.Changes {
+			switch c.Change {
+			case changeFailed:
+				fmt.Fprintf(&b, ":red_circle: %v %v failed: %v\n", c.Name, c.API, c.Error)
+			case changeRecovered:
+				fmt.Fprintf(&b, ":large_green_circle: %v %v recovered (%.0fms)\n", c.Name, c.API, c.AfterMS)
+			case changeRegressed:
+				fmt.Fprintf(&b, ":large_yellow_circle: %v %v slower: %.0fms -> %.0fms\n", c.Name, c.API, c.BeforeMS, c.AfterMS)
+			}
+		}
+	}
+
+	if n.hasFailures() {
+		b.WriteString("\n*Failures*\n")
+		for _, v := range n.Results {
+			for _, vr := range v.APIResults {
+				if len(vr.Error) > 0 {
+					fmt.Fprintf(&b, "• %v %v: `%v`\n", v.Name, vr.API, vr.Error)
+				}
+			}
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+func average(ds []time.Duration) time.Duration {
+	if len(ds) <= 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range ds {
+		total += d
+	}
+	return total / time.Duration(len(ds))
+}
+
+// percentile returns the nearest-rank p-th percentile (0 < p <= 100)
+// of the durations
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) <= 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
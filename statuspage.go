@@ -0,0 +1,211 @@
+package main
+
+import (
+	_ "embed"
+	"html/template"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	//go:embed statuspage.html
+	statusPageTemplate string
+
+	statusPageOut   string
+	statusPageDays  int
+	statusPageTitle string
+)
+
+func newStatusPageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "statuspage",
+		Short: "Write a static html status page of the validators from the runs saved with --store",
+		Long: `Write a static html status page of the validators from the runs saved with
+--store: the status of each validator in the latest run and its daily
+availability, publishable on any static hosting.`,
+		Args: cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			writeStatusPage()
+		},
+	}
+	cmd.Flags().StringVar(&storePath, "store", "", "sqlite database the runs were saved to")
+	cmd.Flags().StringVarP(&statusPageOut, "out", "o", "index.html", "html file written")
+	cmd.Flags().IntVar(&statusPageDays, "days", 90, "number of days of availability shown")
+	cmd.Flags().StringVar(&statusPageTitle, "title", "", "title of the page, the name of the network followed by validators if empty")
+	_ = cmd.MarkFlagRequired("store")
+	return cmd
+}
+
+// statusPage is the data of the status page template
+type statusPage struct {
+	Title       string
+	GeneratedAt time.Time
+	LastRun     time.Time
+	Days        int
+	// Down and Degraded count the validators of the latest run
+	Down, Degraded int
+	Validators     []statusPageValidator
+}
+
+type statusPageValidator struct {
+	Name string
+	// Status is the one of the latest run, unknown if the validator
+	// was not part of it
+	Status       string
+	Availability float64
+	Days         []statusPageDay
+}
+
+// statusPageDay is the availability of a validator over a day, Checks
+// being 0 for the days without stored run
+type statusPageDay struct {
+	Date         time.Time
+	Checks       int
+	Availability float64
+}
+
+// Class returns the css class of the bar of the day
+func (d statusPageDay) Class() string {
+	switch {
+	case d.Checks <= 0:
+		return "none"
+	case d.Availability >= 99.5:
+		return "up"
+	case d.Availability >= 95:
+		return "degraded"
+	default:
+		return "down"
+	}
+}
+
+func writeStatusPage() {
+	network, _ := loadConfig()
+	if statusPageDays <= 0 {
+		fatalConfig("invalid number of days: %v", statusPageDays)
+	}
+	tmpl, err := template.New("statuspage").Parse(statusPageTemplate)
+	if err != nil {
+		fatalConfig("invalid status page template: %v", err)
+	}
+
+	st, err := openStore(storePath)
+	if err != nil {
+		fatalConfig("could not open store: %v", err)
+	}
+	defer st.close()
+
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, -statusPageDays+1)
+	res, err := st.results(network, from)
+	if err != nil {
+		fatalConfig("could not read history: %v", err)
+	}
+	latest, err := latestCompleteRun(st, network)
+	if err != nil {
+		fatalConfig("could not read history: %v", err)
+	}
+
+	page := newStatusPage(network, res, latest, from, statusPageDays)
+	page.GeneratedAt = now
+	if len(statusPageTitle) > 0 {
+		page.Title = statusPageTitle
+	}
+
+	f, err := os.Create(statusPageOut)
+	if err != nil {
+		fatalConfig("could not create status page: %v", err)
+	}
+	if err := tmpl.Execute(f, page); err != nil {
+		f.Close()
+		fatalConfig("could not write status page: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		fatalConfig("could not write status page: %v", err)
+	}
+}
+
+// latestCompleteRun returns the most recent run which was not
+// interrupted, an empty run if none was stored
+func latestCompleteRun(st *store, network string) (run, error) {
+	runs, err := st.runs(network, 20)
+	if err != nil {
+		return run{}, err
+	}
+	for _, r := range runs {
+		if !r.Partial {
+			latest, _, err := st.loadRun(r.ID)
+			return latest, err
+		}
+	}
+	return run{}, nil
+}
+
+// newStatusPage aggregates the stored results per validator and day,
+// leaving out the partial runs
+func newStatusPage(network string, res []storedResult, latest run, from time.Time, days int) statusPage {
+	page := statusPage{
+		Title:   network + " validators",
+		LastRun: latest.Timestamp,
+		Days:    days,
+	}
+
+	status := map[string]string{}
+	for _, v := range latest.Results {
+		status[v.Name] = v.status()
+		switch status[v.Name] {
+		case "down":
+			page.Down++
+		case "degraded":
+			page.Degraded++
+		}
+	}
+
+	type counts struct{ checks, failures int }
+	perDay := map[string][]counts{}
+	for _, r := range res {
+		day := int(r.StartedAt.UTC().Sub(from) / (24 * time.Hour))
+		if r.Partial || day < 0 || day >= days {
+			continue
+		}
+		if _, ok := perDay[r.Validator]; !ok {
+			perDay[r.Validator] = make([]counts, days)
+		}
+		perDay[r.Validator][day].checks++
+		if r.Status == apiStatusError {
+			perDay[r.Validator][day].failures++
+		}
+	}
+	// the validators of the latest run are listed even without history
+	for name := range status {
+		if _, ok := perDay[name]; !ok {
+			perDay[name] = make([]counts, days)
+		}
+	}
+
+	for name, cs := range perDay {
+		v := statusPageValidator{Name: name, Status: status[name]}
+		if len(v.Status) <= 0 {
+			v.Status = "unknown"
+		}
+		var checks, failures int
+		for i, c := range cs {
+			d := statusPageDay{Date: from.AddDate(0, 0, i), Checks: c.checks}
+			if c.checks > 0 {
+				d.Availability = 100 * float64(c.checks-c.failures) / float64(c.checks)
+			}
+			v.Days = append(v.Days, d)
+			checks += c.checks
+			failures += c.failures
+		}
+		if checks > 0 {
+			v.Availability = 100 * float64(checks-failures) / float64(checks)
+		}
+		page.Validators = append(page.Validators, v)
+	}
+	sort.Slice(page.Validators, func(i, j int) bool { return page.Validators[i].Name < page.Validators[j].Name })
+	return page
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	network TEXT NOT NULL,
+	started_at INTEGER NOT NULL,
+	tool_version TEXT NOT NULL,
+	partial INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS runs_started_at ON runs(started_at);
+
+CREATE TABLE IF NOT EXISTS results (
+	run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+	validator TEXT NOT NULL,
+	api TEXT NOT NULL,
+	status TEXT NOT NULL,
+	latency_ms REAL NOT NULL,
+	dns_ms REAL NOT NULL,
+	connect_ms REAL NOT NULL,
+	tls_ms REAL NOT NULL,
+	request_ms REAL NOT NULL,
+	attempts INTEGER NOT NULL,
+	error TEXT NOT NULL DEFAULT '',
+	details TEXT NOT NULL DEFAULT '{}'
+);
+CREATE INDEX IF NOT EXISTS results_run_id ON results(run_id);
+`
+
+// store persists the results of every run in a sqlite database
+type store struct {
+	db *sql.DB
+}
+
+func openStore(path string) (*store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// sqlite allows a single writer, serializing the connections avoids
+	// busy errors between the runs of the server
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(storeSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create schema: %w", err)
+	}
+	return &store{db: db}, nil
+}
+
+func (s *store) close() error {
+	return s.db.Close()
+}
+
+// saveRun stores the report of a run, returning the id of the run
+func (s *store) saveRun(r report) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ins, err := tx.Exec(
+		"INSERT INTO runs (network, started_at, tool_version, partial) VALUES (?, ?, ?, ?)",
+		r.Network, r.Timestamp.UnixMilli(), r.ToolVersion, r.Partial,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := ins.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO results
+		(run_id, validator, api, status, latency_ms, dns_ms, connect_ms, tls_ms, request_ms, attempts, error, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, v := range r.Validators {
+		for _, a := range v.APIResults {
+			details, err := json.Marshal(a.Details)
+			if err != nil {
+				return 0, err
+			}
+			if a.Details == nil {
+				details = []byte("{}")
+			}
+			_, err = stmt.Exec(
+				id, v.Name, a.API, a.Status, a.TimeTakenMS,
+				a.Timings.DNSMS, a.Timings.ConnectMS, a.Timings.TLSMS, a.Timings.RequestMS,
+				a.Attempts, a.Error, string(details),
+			)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// storedResult is the result of a check as saved in the store
+type storedResult struct {
+	RunID     int64
+	StartedAt time.Time
+	Validator string
+	API       string
+	Status    string
+	LatencyMS float64
+	DNSMS     float64
+	ConnectMS float64
+	TLSMS     float64
+	RequestMS float64
+	Attempts  int
+	Error     string
+	// Partial is set on the results of an interrupted run, whose
+	// cancelled checks are not failures of the validators
+	Partial bool
+}
+
+// results returns the results of the runs of the network started
+// since the given time, from the oldest run
+func (s *store) results(network string, since time.Time) ([]storedResult, error) {
+	rows, err := s.db.Query(`SELECT runs.id, runs.started_at, runs.partial, results.validator, results.api, results.status,
+			results.latency_ms, results.dns_ms, results.connect_ms, results.tls_ms, results.request_ms, results.attempts, results.error
+		FROM results JOIN runs ON runs.id = results.run_id
+		WHERE runs.network = ? AND runs.started_at >= ?
+		ORDER BY runs.started_at, runs.id`,
+		network, since.UnixMilli(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []storedResult
+	for rows.Next() {
+		var (
+			r         storedResult
+			startedAt int64
+		)
+		if err := rows.Scan(
+			&r.RunID, &startedAt, &r.Partial, &r.Validator, &r.API, &r.Status,
+			&r.LatencyMS, &r.DNSMS, &r.ConnectMS, &r.TLSMS, &r.RequestMS, &r.Attempts, &r.Error,
+		); err != nil {
+			return nil, err
+		}
+		r.StartedAt = time.UnixMilli(startedAt)
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// prune deletes the runs started before the given time with their
+// results, returning the number of deleted runs
+func (s *store) prune(before time.Time) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM runs WHERE started_at < ?", before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// vacuum reclaims the space of the deleted runs
+func (s *store) vacuum() error {
+	_, err := s.db.Exec("VACUUM")
+	return err
+}
+
+// storedRun summarizes a stored run
+type storedRun struct {
+	ID        int64
+	StartedAt time.Time
+	Partial   bool
+	Checks    int
+	Failures  int
+}
+
+// runs returns the most recent runs of the network, from the most
+// recent one
+func (s *store) runs(network string, limit int) ([]storedRun, error) {
+	rows, err := s.db.Query(`SELECT runs.id, runs.started_at, runs.partial, COUNT(results.run_id), COALESCE(SUM(results.status = ?), 0)
+		FROM runs LEFT JOIN results ON runs.id = results.run_id
+		WHERE runs.network = ?
+		GROUP BY runs.id
+		ORDER BY runs.started_at DESC, runs.id DESC
+		LIMIT ?`,
+		apiStatusError, network, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []storedRun
+	for rows.Next() {
+		var (
+			r         storedRun
+			startedAt int64
+		)
+		if err := rows.Scan(&r.ID, &startedAt, &r.Partial, &r.Checks, &r.Failures); err != nil {
+			return nil, err
+		}
+		r.StartedAt = time.UnixMilli(startedAt)
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// loadRun returns a stored run and the network it was run on
+func (s *store) loadRun(id int64) (run, string, error) {
+	var (
+		network   string
+		startedAt int64
+	)
+	err := s.db.QueryRow("SELECT network, started_at FROM runs WHERE id = ?", id).Scan(&network, &startedAt)
+	if err == sql.ErrNoRows {
+		return run{}, "", fmt.Errorf("no run %d", id)
+	} else if err != nil {
+		return run{}, "", err
+	}
+
+	rows, err := s.db.Query(`SELECT validator, api, latency_ms, dns_ms, connect_ms, tls_ms, request_ms, attempts, error, details
+		FROM results WHERE run_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return run{}, "", err
+	}
+	defer rows.Close()
+
+	r := run{Timestamp: time.UnixMilli(startedAt)}
+	index := map[string]int{}
+	for rows.Next() {
+		var (
+			name, details                           string
+			latency, dns, connect, tlsMS, requestMS float64
+			a                                       aPIResult
+		)
+		if err := rows.Scan(&name, &a.API, &latency, &dns, &connect, &tlsMS, &requestMS, &a.Attempts, &a.Error, &details); err != nil {
+			return run{}, "", err
+		}
+		a.TimeTaken = fromMS(latency)
+		a.Timings = timings{DNS: fromMS(dns), Connect: fromMS(connect), TLS: fromMS(tlsMS), Request: fromMS(requestMS)}
+		if err := json.Unmarshal([]byte(details), &a.Details); err != nil {
+			return run{}, "", err
+		}
+
+		i, ok := index[name]
+		if !ok {
+			i = len(r.Results)
+			index[name] = i
+			r.Results = append(r.Results, results{Name: name})
+		}
+		r.Results[i].APIResults = append(r.Results[i].APIResults, a)
+	}
+	return r, network, rows.Err()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const telegramAPI = "https://api.telegram.org"
+
+type telegramConfig struct {
+	BotToken string `json:"bot_token"`
+	ChatID   string `json:"chat_id"`
+}
+
+// telegramNotifier sends a message when validator APIs go down or
+// recover, or about every failed API without a previous run
+type telegramNotifier struct {
+	cfg telegramConfig
+}
+
+func (t *telegramNotifier) name() string { return "telegram" }
+
+// telegramMessage lists the APIs which went down or recovered, empty if
+// none did
+func telegramMessage(n notification) string {
+	var b strings.Builder
+	if n.FirstRun {
+		for _, v := range n.Results {
+			for _, vr := range v.APIResults {
+				if len(vr.Error) > 0 {
+					fmt.Fprintf(&b, "🔴 %v %v %v is down: %v\n", n.Network, v.Name, vr.API, vr.Error)
+				}
+			}
+		}
+	}
+	for _, c := range n.Changes {
+		switch c.Change {
+		case changeFailed:
+			fmt.Fprintf(&b, "🔴 %v %v %v is down: %v\n", n.Network, c.Name, c.API, c.Error)
+		case changeRecovered:
+			fmt.Fprintf(&b, "🟢 %v %v %v recovered (%.0fms)\n", n.Network, c.Name, c.API, c.AfterMS)
+		}
+	}
+	return b.String()
+}
+
+func (t *telegramNotifier) notify(ctx context.Context, n notification) error {
+	text := telegramMessage(n)
+	if len(text) <= 0 {
+		return nil
+	}
+
+	url := fmt.Sprintf("%v/bot%v/sendMessage", telegramAPI, t.cfg.BotToken)
+	err := postJSON(ctx, url, nil, map[string]string{
+		"chat_id": t.cfg.ChatID,
+		"text":    text,
+	})
+	if err != nil {
+		// the token is part of the url, keep it out of the logs
+		return errors.New(strings.ReplaceAll(err.Error(), t.cfg.BotToken, "<token>"))
+	}
+	return nil
+}
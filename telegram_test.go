@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTelegramFirstRun checks the failures of a run without a previous
+// one are sent, as a one-shot check has no changes to report
+func TestTelegramFirstRun(t *testing.T) {
+	n := notification{
+		Network:  "testnet",
+		Results:  []results{{Name: "v", APIResults: []aPIResult{{API: "rest", Error: "connection refused"}, {API: "gql"}}}},
+		FirstRun: true,
+	}
+	msg := telegramMessage(n)
+	if !strings.Contains(msg, "v rest is down: connection refused") || strings.Contains(msg, "gql") {
+		t.Errorf("message %q, expected the rest failure only", msg)
+	}
+
+	n.FirstRun = false
+	if msg := telegramMessage(n); len(msg) > 0 {
+		t.Errorf("message %q without changes", msg)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	apiStatusOK       = "ok"
+	apiStatusWarning  = "warning"
+	apiStatusCritical = "critical"
+	apiStatusError    = "error"
+)
+
+var (
+	warnThresholds = thresholds{def: 500 * time.Millisecond}
+	critThresholds = thresholds{def: time.Second}
+)
+
+// thresholds is a latency limit with optional per API overrides,
+// set from a flag such as "500ms" or "1s,core=300ms,gql=2s"
+type thresholds struct {
+	def    time.Duration
+	perAPI map[string]time.Duration
+}
+
+func (t *thresholds) Type() string {
+	return "thresholds"
+}
+
+func (t *thresholds) String() string {
+	if t == nil {
+		return ""
+	}
+	parts := []string{t.def.String()}
+	apis := make([]string, 0, len(t.perAPI))
+	for api := range t.perAPI {
+		apis = append(apis, api)
+	}
+	sort.Strings(apis)
+	for _, api := range apis {
+		parts = append(parts, fmt.Sprintf("%v=%v", api, t.perAPI[api]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (t *thresholds) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		api, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			api, value = "", api
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid threshold %q: %w", part, err)
+		}
+		if len(api) <= 0 {
+			t.def = d
+			continue
+		}
+		if t.perAPI == nil {
+			t.perAPI = map[string]time.Duration{}
+		}
+		t.perAPI[api] = d
+	}
+	return nil
+}
+
+// setDefault sets the threshold of the API unless already set
+func (t *thresholds) setDefault(api string, d time.Duration) {
+	if t.perAPI == nil {
+		t.perAPI = map[string]time.Duration{}
+	}
+	if _, ok := t.perAPI[api]; !ok {
+		t.perAPI[api] = d
+	}
+}
+
+func (t thresholds) get(api string) time.Duration {
+	if d, ok := t.perAPI[api]; ok {
+		return d
+	}
+	return t.def
+}
+
+// apiStatus classifies a result against the warning and critical
+// latency thresholds of its API
+func apiStatus(res aPIResult) string {
+	switch {
+	case len(res.Error) > 0:
+		return apiStatusError
+	case res.TimeTaken >= critThresholds.get(res.API):
+		return apiStatusCritical
+	case res.TimeTaken >= warnThresholds.get(res.API):
+		return apiStatusWarning
+	default:
+		return apiStatusOK
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindClient   = 3
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// runTrace records a run of the checks as a trace: a span for the run,
+// a child span per check, itself split in dns, connect, tls and request
+// spans
+type runTrace struct {
+	mu      sync.Mutex
+	traceID string
+	root    otlpSpan
+	spans   []otlpSpan
+}
+
+// newRunTrace starts the trace of a run, nil if no OTLP endpoint is
+// configured
+func newRunTrace(start time.Time) *runTrace {
+	if len(otlpURL("traces")) <= 0 {
+		return nil
+	}
+	t := &runTrace{traceID: randomID(16)}
+	t.root = otlpSpan{
+		TraceID:           t.traceID,
+		SpanID:            randomID(8),
+		Name:              "run",
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: unixNano(start),
+	}
+	return t
+}
+
+// addCheck records the span of a check which ran between start and end
+func (t *runTrace) addCheck(name string, r aPIResult, start, end time.Time) {
+	if t == nil {
+		return
+	}
+
+	check := otlpSpan{
+		TraceID:           t.traceID,
+		SpanID:            randomID(8),
+		ParentSpanID:      t.root.SpanID,
+		Name:              "check " + r.API,
+		Kind:              otlpSpanKindClient,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes: []otlpAttribute{
+			otlpString("vega.validator", name),
+			otlpString("vega.api", r.API),
+			otlpInt("vega.attempts", int64(r.Attempts)),
+		},
+		Status: otlpStatus{Code: otlpStatusOK},
+	}
+	for k, v := range r.Details {
+		check.Attributes = append(check.Attributes, otlpString("vega."+k, v))
+	}
+	if len(r.Error) > 0 {
+		check.Status = otlpStatus{Code: otlpStatusError, Message: r.Error}
+	}
+
+	spans := []otlpSpan{check}
+	// the phases are sequential and measured on the last attempt, they
+	// are laid out backwards from the end of the check
+	phases := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"dns", r.Timings.DNS},
+		{"connect", r.Timings.Connect},
+		{"tls", r.Timings.TLS},
+		{"request", r.Timings.Request},
+	}
+	phaseStart := end.Add(-r.Timings.total())
+	for _, p := range phases {
+		if p.d <= 0 {
+			continue
+		}
+		spans = append(spans, otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            randomID(8),
+			ParentSpanID:      check.SpanID,
+			Name:              p.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: unixNano(phaseStart),
+			EndTimeUnixNano:   unixNano(phaseStart.Add(p.d)),
+		})
+		phaseStart = phaseStart.Add(p.d)
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, spans...)
+	t.mu.Unlock()
+}
+
+// export ends the run span at end and sends the trace to the OTLP
+// endpoint
+func (t *runTrace) export(end time.Time) error {
+	if t == nil {
+		return nil
+	}
+	t.root.EndTimeUnixNano = unixNano(end)
+
+	payload := map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": otlpResource(),
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]string{"name": "check_validator_setup", "version": version},
+				"spans": append([]otlpSpan{t.root}, t.spans...),
+			}},
+		}},
+	}
+
+	return otlpExport("traces", payload)
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
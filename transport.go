@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var (
+	caFile             string
+	insecureSkipVerify bool
+	resolverAddr       string
+	sourceIP           string
+	ipv4Only           bool
+	ipv6Only           bool
+	connectTimeout     time.Duration
+	requestTimeout     time.Duration
+)
+
+var (
+	// clientTLS is the tls configuration shared by the http and grpc
+	// checks
+	clientTLS = &tls.Config{}
+	// resolver resolves the addresses of the validators
+	resolver = net.DefaultResolver
+	// dialer establishes the connections of the http and grpc checks
+	dialer = &net.Dialer{}
+	// localIP is the address the connections are bound to, any if nil
+	localIP net.IP
+)
+
+// addTransportFlags registers the flags controlling how the checks
+// connect to the validators
+func addTransportFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&caFile, "ca-file", "", "pem file of certificate authorities trusted by the tls checks in addition to the system ones")
+	fs.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "INSECURE: do not verify the certificates of the tls checks, only meant for self-signed staging endpoints")
+	fs.StringVar(&resolverAddr, "resolver", "", "dns server resolving the validators instead of the system resolver (e.g: 1.1.1.1:53), or a DNS-over-HTTPS url (e.g: https://cloudflare-dns.com/dns-query)")
+	fs.StringVar(&sourceIP, "source-ip", "", "local address the connections of the checks are bound to, on multi-homed hosts")
+	fs.DurationVar(&connectTimeout, "connect-timeout", 0, "timeout of the tcp connection and tls handshake of each check, only bounded by the check timeout if 0")
+	fs.DurationVar(&requestTimeout, "request-timeout", 0, "timeout of the request of each check once connected, only bounded by the check timeout if 0")
+	fs.BoolVarP(&ipv4Only, "ipv4", "4", false, "only connect to the validators over ipv4")
+	fs.BoolVarP(&ipv6Only, "ipv6", "6", false, "only connect to the validators over ipv6")
+}
+
+// setupTransport applies the transport flags to the connections of the
+// checks
+func setupTransport() error {
+	if ipv4Only && ipv6Only {
+		return errors.New("-4 and -6 are mutually exclusive")
+	}
+	if len(caFile) > 0 {
+		buf, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("could not read the certificate authorities: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(buf) {
+			return fmt.Errorf("no certificate found in %v", caFile)
+		}
+		clientTLS.RootCAs = pool
+	}
+	if insecureSkipVerify {
+		slog.Warn("tls certificates are not verified, the checks do not guarantee the validators are who they claim to be")
+		clientTLS.InsecureSkipVerify = true
+	}
+	dialer.Timeout = connectTimeout
+	httpTransport.TLSHandshakeTimeout = connectTimeout
+	httpTransport.ResponseHeaderTimeout = requestTimeout
+	if len(sourceIP) > 0 {
+		localIP = net.ParseIP(sourceIP)
+		if localIP == nil {
+			return fmt.Errorf("invalid source ip: %v", sourceIP)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+	}
+	if len(resolverAddr) > 0 {
+		r, err := newResolver(resolverAddr)
+		if err != nil {
+			return fmt.Errorf("invalid resolver: %w", err)
+		}
+		resolver = r
+		dialer.Resolver = r
+	}
+	return nil
+}
+
+// connectContext bounds the connection establishment by
+// --connect-timeout, if set
+func connectContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if connectTimeout > 0 {
+		return context.WithTimeout(ctx, connectTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// requestContext bounds a request by --request-timeout, if set
+func requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if requestTimeout > 0 {
+		return context.WithTimeout(ctx, requestTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// ipFamily returns the suffix of the networks forced by -4 or -6,
+// empty if any family is allowed
+func ipFamily() string {
+	switch {
+	case ipv4Only:
+		return "4"
+	case ipv6Only:
+		return "6"
+	}
+	return ""
+}
+
+// dialContext dials the address using the family forced by -4 or -6
+func dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if network == "tcp" {
+		network += ipFamily()
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// newResolver returns a resolver querying the dns server at the
+// address, or the DNS-over-HTTPS server at the url
+func newResolver(address string) (*net.Resolver, error) {
+	if strings.HasPrefix(address, "https://") {
+		if _, err := url.Parse(address); err != nil {
+			return nil, err
+		}
+		d := &net.Dialer{}
+		if localIP != nil {
+			d.LocalAddr = &net.TCPAddr{IP: localIP}
+		}
+		client := &http.Client{Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DialContext:       d.DialContext,
+			ForceAttemptHTTP2: true,
+		}}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(context.Context, string, string) (net.Conn, error) {
+				return &dohConn{url: address, client: client}, nil
+			},
+		}, nil
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := &net.Dialer{}
+			if localIP != nil {
+				if strings.HasPrefix(network, "udp") {
+					d.LocalAddr = &net.UDPAddr{IP: localIP}
+				} else {
+					d.LocalAddr = &net.TCPAddr{IP: localIP}
+				}
+			}
+			return d.DialContext(ctx, network, address)
+		},
+	}, nil
+}
+
+// dohConn exchanges the dns messages of the go resolver with a
+// DNS-over-HTTPS server (RFC 8484). Not being a net.PacketConn, the
+// resolver frames the messages as over tcp, prefixed by their length.
+type dohConn struct {
+	url      string
+	client   *http.Client
+	deadline time.Time
+	query    bytes.Buffer
+	answer   *bytes.Reader
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.answer = nil
+	return c.query.Write(b)
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.answer == nil {
+		answer, err := c.exchange()
+		if err != nil {
+			return 0, err
+		}
+		c.answer = bytes.NewReader(answer)
+	}
+	return c.answer.Read(b)
+}
+
+// exchange posts the buffered query, returning the answer prefixed by
+// its length
+func (c *dohConn) exchange() ([]byte, error) {
+	buf := c.query.Bytes()
+	if len(buf) < 2 || len(buf) < 2+int(binary.BigEndian.Uint16(buf)) {
+		return nil, errors.New("incomplete dns query")
+	}
+	msg := buf[2 : 2+binary.BigEndian.Uint16(buf)]
+	c.query.Reset()
+
+	ctx := context.Background()
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected DNS-over-HTTPS status code: %v", resp.StatusCode)
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(answer))), answer...), nil
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr(c.url) }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr(c.url) }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { c.deadline = t; return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"golang.org/x/term"
+)
+
+const sparkBars = "▁▂▃▄▅▆▇█"
+
+// tui is an interactive dashboard refreshing the checks every
+// interval, with a per validator drill-down
+type tui struct {
+	network    string
+	validators []validator
+
+	latest  map[string]results
+	history map[string][]results
+
+	selected int
+	details  bool
+	running  int
+	// checking holds the validators of the runs in flight, not checked
+	// again until they complete
+	checking map[string]bool
+	lastRun  time.Time
+}
+
+func runTUI(network string, validators []validator) error {
+	// the selection and the drill-down index the validators
+	if len(validators) <= 0 {
+		return errors.New("no validator to check")
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("the tui requires a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	// hide the cursor while running
+	fmt.Print("\033[?25l")
+	defer fmt.Print("\033[?25h" + clearScreen)
+
+	t := &tui{
+		network:    network,
+		validators: validators,
+		latest:     map[string]results{},
+		history:    map[string][]results{},
+		checking:   map[string]bool{},
+	}
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	// the runs in flight are cancelled on exit
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan []results)
+	check := func(vs []validator) {
+		var pending []validator
+		for _, v := range vs {
+			if !t.checking[v.Name] {
+				t.checking[v.Name] = true
+				pending = append(pending, v)
+			}
+		}
+		if len(pending) <= 0 {
+			return
+		}
+		t.running++
+		go func() {
+			runCtx, cancelRun := runContext()
+			defer cancelRun()
+			stop := context.AfterFunc(ctx, cancelRun)
+			defer stop()
+			res := runChecks(runCtx, pending, checks, nil)
+			select {
+			case done <- res:
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	check(validators)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		t.render()
+
+		select {
+		case <-ticker.C:
+			check(validators)
+		case res := <-done:
+			t.running--
+			for _, v := range res {
+				delete(t.checking, v.Name)
+			}
+			t.record(res)
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch k {
+			case "q", "ctrl-c":
+				return nil
+			case "up", "k":
+				if t.selected > 0 {
+					t.selected--
+				}
+			case "down", "j":
+				if t.selected < len(validators)-1 {
+					t.selected++
+				}
+			case "enter":
+				t.details = true
+			case "esc":
+				t.details = false
+			case "r":
+				check([]validator{validators[t.selected]})
+			case "R":
+				check(validators)
+			}
+		}
+	}
+}
+
+// record saves the results of a run, keeping at most --history-size
+// results per validator
+func (t *tui) record(res []results) {
+	t.lastRun = time.Now()
+	for _, v := range res {
+		t.latest[v.Name] = v
+		h := append(t.history[v.Name], v)
+		if len(h) > historySize {
+			h = h[len(h)-historySize:]
+		}
+		t.history[v.Name] = h
+	}
+}
+
+func (t *tui) render() {
+	var b strings.Builder
+
+	status := "last run " + t.lastRun.Format("15:04:05")
+	if t.lastRun.IsZero() {
+		status = "no results yet"
+	}
+	if t.running > 0 {
+		status += ", checking..."
+	}
+	fmt.Fprintf(&b, "vega %v validators - %v\n\n", t.network, status)
+
+	if t.details {
+		t.renderDetails(&b)
+		b.WriteString("\nesc back  r re-check  q quit\n")
+	} else {
+		t.renderList(&b)
+		b.WriteString("\n↑/↓ select  enter details  r re-check  R re-check all  q quit\n")
+	}
+
+	// the terminal is in raw mode, lines need a carriage return
+	fmt.Print(clearScreen + strings.ReplaceAll(b.String(), "\n", "\r\n"))
+}
+
+func (t *tui) renderList(w io.Writer) {
+	apis := t.apis()
+
+	header := table.Row{"", "validator"}
+	for _, api := range apis {
+		header = append(header, apiHeader(api))
+	}
+	header = append(header, "status")
+
+	tw := table.NewWriter()
+	tw.AppendHeader(header)
+	for i, v := range t.validators {
+		cursor := " "
+		if i == t.selected {
+			cursor = ">"
+		}
+		row := table.Row{cursor, v.Name}
+		res, ok := t.latest[v.Name]
+		resMap := map[string]aPIResult{}
+		for _, vr := range res.APIResults {
+			resMap[vr.API] = vr
+		}
+		for _, api := range apis {
+			if vr, ok := resMap[api]; ok {
+				row = append(row, coloredDuration(vr))
+			} else {
+				row = append(row, "-")
+			}
+		}
+		if ok {
+			row = append(row, res.status())
+		} else {
+			row = append(row, "-")
+		}
+		tw.AppendRow(row)
+	}
+	fmt.Fprintln(w, tw.Render())
+}
+
+func (t *tui) renderDetails(w io.Writer) {
+	v := t.validators[t.selected]
+	fmt.Fprintf(w, "%v\n  grpc: %v\n  rest: %v\n  gql:  %v\n\n", v.Name, v.GRPC, v.REST, v.GQL)
+
+	res, ok := t.latest[v.Name]
+	if !ok {
+		fmt.Fprintln(w, "no results yet")
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"api", "latency", "history", "details", "error"})
+	for _, vr := range res.APIResults {
+		past := []aPIResult{}
+		for _, h := range t.history[v.Name] {
+			for _, hr := range h.APIResults {
+				if hr.API == vr.API {
+					past = append(past, hr)
+				}
+			}
+		}
+
+		details := []string{}
+		for k, d := range vr.Details {
+			details = append(details, k+"="+d)
+		}
+		sort.Strings(details)
+
+		tw.AppendRow(table.Row{
+			apiHeader(vr.API),
+			coloredDuration(vr),
+			sparkline(past),
+			strings.Join(details, " "),
+			vr.Error,
+		})
+	}
+	fmt.Fprintln(w, tw.Render())
+}
+
+// apis returns the APIs checked, in order
+func (t *tui) apis() []string {
+	res := make([]results, 0, len(t.latest))
+	for _, v := range t.validators {
+		if r, ok := t.latest[v.Name]; ok {
+			res = append(res, r)
+		}
+	}
+	return resultAPIs(res)
+}
+
+// sparkline renders the latencies of the results relative to the
+// slowest one, failed checks are shown as x
+func sparkline(res []aPIResult) string {
+	var max time.Duration
+	for _, r := range res {
+		if len(r.Error) <= 0 && r.TimeTaken > max {
+			max = r.TimeTaken
+		}
+	}
+
+	bars := []rune(sparkBars)
+	var b strings.Builder
+	for _, r := range res {
+		if len(r.Error) > 0 {
+			b.WriteRune('x')
+			continue
+		}
+		i := len(bars) - 1
+		if max > 0 {
+			i = int(int64(r.TimeTaken) * int64(len(bars)-1) / int64(max))
+		}
+		b.WriteRune(bars[i])
+	}
+	return b.String()
+}
+
+// readKeys translates the raw terminal input into key names
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+
+	buf := make([]byte, 8)
+	for {
+		n, err := r.Read(buf)
+		if err != nil || n <= 0 {
+			return
+		}
+		switch {
+		case n >= 3 && buf[0] == 27 && buf[1] == '[':
+			switch buf[2] {
+			case 'A':
+				keys <- "up"
+			case 'B':
+				keys <- "down"
+			}
+		case buf[0] == 27:
+			keys <- "esc"
+		case buf[0] == 3:
+			keys <- "ctrl-c"
+		case buf[0] == '\r' || buf[0] == '\n':
+			keys <- "enter"
+		default:
+			keys <- string(buf[:1])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var networkName string
+
+func newValidatorsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validators",
+		Short: "Inspect the validators of the configuration",
+	}
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the names, endpoints and tags of the validators, the names being the values of --only",
+		Args:  cobra.NoArgs,
+		PreRunE: func(*cobra.Command, []string) error {
+			switch networkName {
+			case "":
+			case "mainnet":
+				testnetConfig = false
+			case "testnet":
+				testnetConfig = true
+			default:
+				return fmt.Errorf("invalid network: %v [mainnet|testnet]", networkName)
+			}
+			return nil
+		},
+		Run: func(*cobra.Command, []string) {
+			network, cfg := loadConfig()
+			fmt.Println(renderValidators(network, cfg.Validators))
+		},
+	}
+	list.Flags().StringVar(&networkName, "network", "", "embedded network configuration to list [mainnet|testnet], the one of --testnet and --config if empty")
+	_ = list.RegisterFlagCompletionFunc("network", cobra.FixedCompletions([]string{"mainnet", "testnet"}, cobra.ShellCompDirectiveNoFileComp))
+	cmd.AddCommand(list)
+	return cmd
+}
+
+func renderValidators(network string, validators []validator) string {
+	t := table.NewWriter()
+	t.SetTitle(fmt.Sprintf("%v, %d validators", network, len(validators)))
+	t.AppendHeader(table.Row{"name", "grpc", "rest", "graphql", "tendermint", "core rest", "tags"})
+	for _, v := range validators {
+		t.AppendRow(table.Row{
+			v.Name, orDash(v.GRPC), orDash(v.REST), orDash(v.GQL), orDash(v.Tendermint), orDash(v.CoreREST),
+			orDash(strings.Join(v.Tags, ", ")),
+		})
+	}
+	return t.Render()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// commit and buildDate are set at build time using -ldflags
+	// "-X main.commit=... -X main.buildDate=...", read from the vcs
+	// information stamped by go build otherwise
+	commit    string
+	buildDate string
+
+	versionJSON bool
+)
+
+// buildInfo describes the binary, so that bug reports and shared
+// results state exactly what was run
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	// Configs are the revisions of the embedded configurations, the
+	// start of the sha256 of their content
+	Configs map[string]string `json:"configs"`
+}
+
+func newBuildInfo() buildInfo {
+	b := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Configs: map[string]string{
+			"mainnet": configRevision(mainnetBuf),
+			"testnet": configRevision(testnetBuf),
+		},
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var modified bool
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if len(b.Commit) <= 0 {
+					b.Commit = s.Value
+				}
+			case "vcs.time":
+				if len(b.BuildDate) <= 0 {
+					b.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if modified && len(commit) <= 0 && len(b.Commit) > 0 {
+			b.Commit += "-dirty"
+		}
+	}
+	return b
+}
+
+// configRevision identifies the content of an embedded configuration
+func configRevision(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:6])
+}
+
+func (b buildInfo) String() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "check_validator_setup %v\n", b.Version)
+	fmt.Fprintf(&s, "commit:  %v\n", orUnknown(b.Commit))
+	fmt.Fprintf(&s, "built:   %v\n", orUnknown(b.BuildDate))
+	fmt.Fprintf(&s, "go:      %v %v\n", b.GoVersion, b.Platform)
+	fmt.Fprintf(&s, "configs: mainnet %v, testnet %v\n", b.Configs["mainnet"], b.Configs["testnet"])
+	return s.String()
+}
+
+func orUnknown(s string) string {
+	if len(s) <= 0 {
+		return "unknown"
+	}
+	return s
+}
+
+func newVersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version, commit and build date of the tool and the revisions of the embedded configurations",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			info := newBuildInfo()
+			if !versionJSON {
+				fmt.Print(info)
+				return
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(info); err != nil {
+				fatalConfig("could not print version: %v", err)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&versionJSON, "json", false, "print the version as json")
+	return cmd
+}
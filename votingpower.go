@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	register(votingPowerCheck{}, profileDeep)
+}
+
+const (
+	detailVotingPower         = "voting_power"
+	detailExpectedVotingPower = "expected_voting_power"
+	detailStakedTotal         = "staked_total"
+)
+
+// networkNodes is the part of the response of the data-node
+// /api/v2/nodes endpoint describing the stake of the validators
+type networkNodes struct {
+	Nodes struct {
+		Edges []struct {
+			Node struct {
+				ID           string `json:"id"`
+				Name         string `json:"name"`
+				TmPubKey     string `json:"tmPubKey"`
+				InfoURL      string `json:"infoUrl"`
+				AvatarURL    string `json:"avatarUrl"`
+				StakedTotal  string `json:"stakedTotal"`
+				RankingScore struct {
+					VotingPower string `json:"votingPower"`
+				} `json:"rankingScore"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"nodes"`
+}
+
+// votingPowerCheck compares the voting power of the node in tendermint
+// with the one the network derives from its stake and score, as
+// reported by the data-node of the validator
+type votingPowerCheck struct{}
+
+func (votingPowerCheck) name() string {
+	return "votingpower"
+}
+
+func (votingPowerCheck) endpoint(v validator) string {
+	return v.Tendermint
+}
+
+func (votingPowerCheck) run(ctx context.Context, v validator) (timings, map[string]string, error) {
+	if len(v.Tendermint) <= 0 {
+		return notConfigured()
+	}
+	var status tendermintStatus
+	t, details, err := tendermintRPC(ctx, v.Tendermint, "status", nil, &status)
+	if err != nil {
+		return t, details, err
+	}
+	info := status.Result.ValidatorInfo
+	details[detailVotingPower] = info.VotingPower
+
+	var nodes networkNodes
+	nt, _, err := getJSON(ctx, v.REST, "api/v2/nodes", nil, &nodes)
+	t.Request += nt.total()
+	if err != nil {
+		return t, details, fmt.Errorf("could not list the nodes of the network: %w", err)
+	}
+
+	var announced []string
+	for _, e := range nodes.Nodes.Edges {
+		n := e.Node
+		if len(info.PubKey.Value) <= 0 || n.TmPubKey != info.PubKey.Value {
+			continue
+		}
+		announced = append(announced, orDash(n.Name)+" ("+n.ID+")")
+		details[detailExpectedVotingPower] = n.RankingScore.VotingPower
+		details[detailStakedTotal] = n.StakedTotal
+	}
+
+	switch {
+	case len(announced) <= 0:
+		if info.VotingPower == "" || info.VotingPower == "0" {
+			return t, details, errors.New("node is not in the validator set")
+		}
+		return t, details, errors.New("tendermint key not announced by any node of the network")
+	case len(announced) > 1:
+		return t, details, fmt.Errorf("tendermint key announced by %d nodes: %v", len(announced), strings.Join(announced, ", "))
+	case details[detailExpectedVotingPower] != info.VotingPower:
+		return t, details, fmt.Errorf("tendermint voting power %v, %v expected from the stake of the node", orDash(info.VotingPower), orDash(details[detailExpectedVotingPower]))
+	}
+	return t, details, nil
+}
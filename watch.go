@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// watch runs the checks every interval forever, re-rendering the
+// output after each run
+func watch(network string, validators []validator, notifiers []notifier, st *store) {
+	history := newRunHistory(historySize)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for n := 1; ; n++ {
+		startedAt := time.Now()
+		ctx, cancel := runContext()
+		res := runChecks(ctx, validators, checks, progress(runSize(validators, checks)))
+		cancel()
+		sortResults(res, sortBy, sortDesc)
+
+		var changes []diffEntry
+		previous, ok := history.latest()
+		if ok {
+			changes = diffResults(newReport(network, previous.Timestamp, previous.Results, nil), res, regressionFactor)
+		}
+		regressions, err := baselineRegressions(st, network, res)
+		if err != nil {
+			slog.Error("could not compare with the baseline", "error", err)
+		}
+		changes = append(changes, regressions...)
+		history.add(run{Timestamp: startedAt, Results: res})
+		pushMetrics(network, run{Timestamp: startedAt, Results: res})
+
+		if output == "human" || output == "emoji" {
+			fmt.Print(clearScreen)
+			fmt.Printf("%v - run %d, last at %v, next in %v\n",
+				network, n, startedAt.Format("15:04:05"), interval)
+		}
+		printOutput(network, startedAt, res, changes, false)
+
+		notifyAll(notifiers, notification{
+			Network:   network,
+			Timestamp: startedAt,
+			Results:   res,
+			Changes:   alertedChanges(changes),
+			FirstRun:  !ok,
+		})
+
+		if len(outFile) > 0 {
+			r := newReport(network, startedAt, res, changes)
+			if err := writeReport(outputPath(outFile, network, startedAt), r); err != nil {
+				slog.Error("could not write results", "error", err)
+			}
+		}
+
+		if st != nil {
+			if _, err := st.saveRun(newReport(network, startedAt, res, changes)); err != nil {
+				slog.Error("could not store results", "error", err)
+			}
+			applyRetention(st)
+		}
+
+		<-ticker.C
+	}
+}
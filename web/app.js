@@ -0,0 +1,234 @@
+// refreshes the results table from /api/v1/results and charts the
+// history of a validator from /api/v1/series
+
+const refreshInterval = 10000;
+
+const headers = {
+  gql: "graphql",
+  corerest: "core rest",
+  gqlcomplexity: "graphql complexity",
+};
+
+function cell(text, className) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function formatLatency(ms) {
+  if (ms >= 1000) {
+    return (ms / 1000).toFixed(2) + "s";
+  }
+  return ms.toFixed(ms < 10 ? 1 : 0) + "ms";
+}
+
+function render(report) {
+  document.title = report.network + " validators";
+  document.getElementById("title").textContent = report.network + " validators";
+
+  const apis = [];
+  for (const v of report.validators) {
+    for (const r of v.api_results) {
+      if (!apis.includes(r.api)) {
+        apis.push(r.api);
+      }
+    }
+  }
+
+  const head = document.querySelector("#results thead");
+  head.replaceChildren();
+  const tr = document.createElement("tr");
+  for (const h of ["validator", ...apis.map((api) => headers[api] || api)]) {
+    const th = document.createElement("th");
+    th.textContent = h;
+    tr.appendChild(th);
+  }
+  head.appendChild(tr);
+
+  const body = document.querySelector("#results tbody");
+  const errors = document.querySelector("#errors tbody");
+  body.replaceChildren();
+  errors.replaceChildren();
+  for (const v of report.validators) {
+    const row = document.createElement("tr");
+    const name = cell(v.name, v.status + " validator-name");
+    name.addEventListener("click", () => selectValidator(v.name));
+    row.appendChild(name);
+    for (const api of apis) {
+      const r = v.api_results.find((r) => r.api === api);
+      if (!r) {
+        row.appendChild(cell("-"));
+        continue;
+      }
+      const td = cell(r.error ? "error" : formatLatency(r.time_taken_ms), r.status);
+      if (r.error) {
+        td.title = r.error;
+        const err = document.createElement("tr");
+        err.append(cell(v.name), cell(headers[api] || api), cell(r.error, "error-message"));
+        errors.appendChild(err);
+      }
+      row.appendChild(td);
+    }
+    body.appendChild(row);
+  }
+  if (!errors.children.length) {
+    const none = document.createElement("tr");
+    none.appendChild(cell("none"));
+    errors.appendChild(none);
+  }
+
+  document.getElementById("alerts").textContent = (report.alerts || []).join(" · ");
+  updateValidators(report.validators.map((v) => v.name));
+}
+
+function updated(timestamp, error) {
+  const el = document.getElementById("updated");
+  el.classList.toggle("stale", Boolean(error));
+  if (!timestamp) {
+    el.textContent = error || "waiting for the first run";
+    return;
+  }
+  const text = "last run " + new Date(timestamp).toLocaleString();
+  el.textContent = error ? text + ", " + error : text;
+}
+
+let lastTimestamp;
+
+async function refresh() {
+  try {
+    const resp = await fetch("api/v1/results");
+    if (resp.status === 503) {
+      updated(lastTimestamp);
+      return;
+    }
+    if (!resp.ok) {
+      throw new Error("http status " + resp.status);
+    }
+    const report = await resp.json();
+    lastTimestamp = report.timestamp;
+    render(report);
+    updated(lastTimestamp);
+  } catch (e) {
+    updated(lastTimestamp, "could not refresh: " + e.message);
+  }
+}
+
+const palette = ["#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"];
+
+function updateValidators(names) {
+  const select = document.getElementById("history-validator");
+  const current = [...select.options].map((o) => o.value);
+  if (current.join() === names.join()) {
+    return;
+  }
+  const selected = select.value;
+  select.replaceChildren(...names.map((n) => new Option(n, n)));
+  select.value = names.includes(selected) ? selected : names[0];
+  if (select.value !== selected) {
+    refreshHistory();
+  }
+}
+
+function selectValidator(name) {
+  document.getElementById("history-validator").value = name;
+  refreshHistory();
+  document.getElementById("history").scrollIntoView();
+}
+
+const svgNS = "http://www.w3.org/2000/svg";
+
+function svgElement(name, attrs) {
+  const el = document.createElementNS(svgNS, name);
+  for (const [k, v] of Object.entries(attrs)) {
+    el.setAttribute(k, v);
+  }
+  return el;
+}
+
+// chart draws a line per series, gaps being left where value returns
+// null
+function chart(container, series, from, to, value, maxValue, format) {
+  const width = 800;
+  const height = 200;
+  const left = 50;
+  const bottom = 20;
+  const svg = svgElement("svg", { viewBox: `0 0 ${width} ${height}`, preserveAspectRatio: "none" });
+  const x = (t) => left + ((t - from) / (to - from)) * (width - left);
+  const y = (v) => (height - bottom) * (1 - v / maxValue);
+
+  for (const v of [0, maxValue / 2, maxValue]) {
+    svg.appendChild(svgElement("line", { class: "axis", x1: left, x2: width, y1: y(v), y2: y(v) }));
+    const label = svgElement("text", { x: 0, y: Math.max(y(v), 10) });
+    label.textContent = format(v);
+    svg.appendChild(label);
+  }
+  for (const t of [from, to]) {
+    const label = svgElement("text", { x: t === from ? left : width - 120, y: height - 4 });
+    label.textContent = new Date(t).toLocaleString();
+    svg.appendChild(label);
+  }
+
+  series.forEach((s, i) => {
+    let d = "";
+    let move = true;
+    for (const p of s.points) {
+      const v = value(p);
+      if (v === null) {
+        move = true;
+        continue;
+      }
+      d += `${move ? "M" : "L"}${x(Date.parse(p.time)).toFixed(1)},${y(v).toFixed(1)} `;
+      move = false;
+    }
+    svg.appendChild(svgElement("path", { d, stroke: palette[i % palette.length] }));
+  });
+  container.replaceChildren(svg);
+}
+
+const rangeMS = { "1h": 3600e3, "24h": 86400e3, "7d": 7 * 86400e3, "30d": 30 * 86400e3 };
+
+async function refreshHistory() {
+  const validator = document.getElementById("history-validator").value;
+  const range = document.getElementById("history-range").value;
+  const message = document.getElementById("history-message");
+  if (!validator) {
+    return;
+  }
+  let series;
+  try {
+    const query = new URLSearchParams({ since: range, validator });
+    const resp = await fetch("api/v1/series?" + query);
+    if (!resp.ok) {
+      throw new Error((await resp.text()).trim() || "http status " + resp.status);
+    }
+    series = await resp.json();
+  } catch (e) {
+    message.textContent = e.message;
+    return;
+  }
+  message.textContent = series.length ? "" : "no stored results for " + validator + " over this period";
+
+  const to = Date.now();
+  const from = to - rangeMS[range];
+  const maxLatency = Math.max(1, ...series.flatMap((s) => s.points.map((p) => p.avg_ms || 0)));
+  chart(document.getElementById("latency-chart"), series, from, to, (p) => p.avg_ms, maxLatency, formatLatency);
+  chart(document.getElementById("availability-chart"), series, from, to, (p) => p.availability, 100, (v) => v + "%");
+
+  const legend = document.getElementById("legend");
+  legend.replaceChildren(...series.map((s, i) => {
+    const span = document.createElement("span");
+    span.textContent = headers[s.api] || s.api;
+    span.style.setProperty("--color", palette[i % palette.length]);
+    return span;
+  }));
+}
+
+document.getElementById("history-validator").addEventListener("change", refreshHistory);
+document.getElementById("history-range").addEventListener("change", refreshHistory);
+
+refresh();
+setInterval(refresh, refreshInterval);
+setInterval(refreshHistory, 6 * refreshInterval);
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"text/template"
+)
+
+type webhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	// Template is the go template rendering the request body from the
+	// json report, TemplateFile loads it from a file instead
+	Template     string `json:"template"`
+	TemplateFile string `json:"template_file"`
+	// OnlyChanges only posts when the status of an API changed since
+	// the previous run
+	OnlyChanges bool `json:"only_changes"`
+}
+
+// webhookNotifier posts a payload rendered from a user template, so
+// any service accepting http requests can receive the results
+type webhookNotifier struct {
+	cfg  webhookConfig
+	tmpl *template.Template
+}
+
+func newWebhookNotifier(cfg webhookConfig) (*webhookNotifier, error) {
+	text := cfg.Template
+	if len(cfg.TemplateFile) > 0 {
+		buf, err := os.ReadFile(cfg.TemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(buf)
+	}
+	if len(text) <= 0 {
+		return nil, errors.New("missing template")
+	}
+
+	tmpl, err := template.New("webhook").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhookNotifier{cfg: cfg, tmpl: tmpl}, nil
+}
+
+// name identifies the webhook by its host, its url often holding a
+// token
+func (w *webhookNotifier) name() string {
+	u, err := url.Parse(w.cfg.URL)
+	if err != nil || len(u.Host) <= 0 {
+		return "webhook"
+	}
+	return "webhook " + u.Host
+}
+
+func (w *webhookNotifier) notify(ctx context.Context, n notification) error {
+	if w.cfg.OnlyChanges && len(n.Changes) <= 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	if err := w.tmpl.Execute(&body, newReport(n.Network, n.Timestamp, n.Results, n.Changes)); err != nil {
+		return err
+	}
+
+	return post(ctx, w.cfg.URL, w.cfg.Headers, body.Bytes())
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestWebhookNoURLLeak checks neither the name of a webhook, which is
+// logged, nor its errors hold the token of its url
+func TestWebhookNoURLLeak(t *testing.T) {
+	const secret = "webhook-secret"
+	w, err := newWebhookNotifier(webhookConfig{URL: "http://127.0.0.1:1/hooks/" + secret, Template: "{}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := w.name(); name != "webhook 127.0.0.1:1" {
+		t.Errorf("name is %q", name)
+	}
+	err = w.notify(context.Background(), notification{Network: "testnet"})
+	if err == nil {
+		t.Fatal("notify succeeded without a server")
+	}
+	if strings.Contains(err.Error(), secret) {
+		t.Errorf("error holds the url: %v", err)
+	}
+
+	_, err = notifiersConfig{Webhooks: []webhookConfig{{URL: "http://example.com/" + secret}}}.build()
+	if err == nil || strings.Contains(err.Error(), secret) {
+		t.Errorf("configuration error %v", err)
+	}
+}